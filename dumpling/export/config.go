@@ -3,7 +3,6 @@
 package export
 
 import (
-	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -14,7 +13,6 @@ import (
 	"text/template"
 	"time"
 
-	"github.com/coreos/go-semver/semver"
 	"github.com/docker/go-units"
 	"github.com/go-sql-driver/mysql"
 	"github.com/pingcap/errors"
@@ -77,46 +75,16 @@ const (
 	flagTransactionalConsistency = "transactional-consistency"
 	flagCompress                 = "compress"
 	flagCsvOutputDialect         = "csv-output-dialect"
+	flagConnCompress             = "conn-compress"
+	flagRowsEstimateMethod       = "rows-estimate-method"
+	flagJSONLBinaryFormat        = "jsonl-binary-format"
+	flagExtStorageHTTPProxy      = "ext-storage-http-proxy"
+	flagExtStorageRequestTimeout = "ext-storage-request-timeout"
 
 	// FlagHelp represents the help flag
 	FlagHelp = "help"
 )
 
-// CSVDialect is the dialect of the CSV output for compatible with different import target
-type CSVDialect int
-
-const (
-	// CSVDialectDefault is the default dialect, which is MySQL/MariaDB/TiDB etc.
-	CSVDialectDefault CSVDialect = iota
-	// CSVDialectSnowflake is the dialect of Snowflake
-	CSVDialectSnowflake
-	// CSVDialectRedshift is the dialect of Redshift
-	CSVDialectRedshift
-	// CSVDialectBigQuery is the dialect of BigQuery
-	CSVDialectBigQuery
-)
-
-// BinaryFormat is the format of binary data
-// Three standard formats are supported: UTF8, HEX and Base64 now.
-type BinaryFormat int
-
-const (
-	// BinaryFormatUTF8 is the default format, format binary data as UTF8 string
-	BinaryFormatUTF8 BinaryFormat = iota
-	// BinaryFormatHEX format binary data as HEX string, e.g. 12ABCD
-	BinaryFormatHEX
-	// BinaryFormatBase64 format binary data as Base64 string, e.g. 123qwer==
-	BinaryFormatBase64
-)
-
-// DialectBinaryFormatMap is the map of dialect and binary format
-var DialectBinaryFormatMap = map[CSVDialect]BinaryFormat{
-	CSVDialectDefault:   BinaryFormatUTF8,
-	CSVDialectSnowflake: BinaryFormatHEX,
-	CSVDialectRedshift:  BinaryFormatHEX,
-	CSVDialectBigQuery:  BinaryFormatBase64,
-}
-
 // Config is the dump config for dumpling
 type Config struct {
 	storage.BackendOptions
@@ -168,6 +136,9 @@ type Config struct {
 	TableFilter         filter.Filter `json:"-"`
 	Where               string
 	FileType            string
+	ConnCompress        ConnCompress
+	RowsEstimateMethod  RowsEstimateMethod
+	JSONLBinaryFormat   BinaryFormat
 	ServerInfo          version.ServerInfo
 	Logger              *zap.Logger        `json:"-"`
 	OutputFileTemplate  *template.Template `json:"-"`
@@ -179,7 +150,7 @@ type Config struct {
 	SessionParams       map[string]any
 	Tables              DatabaseTables
 	CollationCompatible string
-	CsvOutputDialect    CSVDialect
+	CsvOutputDialect    *CSVDialect
 
 	Labels        prometheus.Labels       `json:"-"`
 	PromFactory   promutil.Factory        `json:"-"`
@@ -187,6 +158,17 @@ type Config struct {
 	ExtStorage    storage.ExternalStorage `json:"-"`
 	MinTLSVersion uint16                  `json:"-"`
 
+	// ExtStorageHTTPProxy, when set, is used as the proxy for the HTTP
+	// client handed to the external storage backend (S3/GCS/Azure).
+	ExtStorageHTTPProxy string
+	// ExtStorageRequestTimeout bounds a single HTTP request made by the
+	// external storage backend. Zero means no timeout is applied.
+	ExtStorageRequestTimeout time.Duration
+
+	// ErrorPolicy controls how the dump loop reacts to errors classified by
+	// ClassifyMySQLError.
+	ErrorPolicy ErrorPolicy
+
 	IOTotalBytes *atomic.Uint64
 	Net          string
 }
@@ -239,6 +221,10 @@ func DefaultConfig() *Config {
 		CollationCompatible:      LooseCollationCompatible,
 		CsvOutputDialect:         CSVDialectDefault,
 		SpecifiedTables:          false,
+		ConnCompress:             ConnCompressNone,
+		RowsEstimateMethod:       RowsEstimateMethodTableStatus,
+		JSONLBinaryFormat:        BinaryFormatUTF8,
+		ErrorPolicy:              DefaultErrorPolicy(),
 		PromFactory:              promutil.NewDefaultFactory(),
 		PromRegistry:             promutil.NewDefaultRegistry(),
 		TransactionalConsistency: true,
@@ -297,6 +283,13 @@ func (conf *Config) GetDriverConfig(db string) *mysql.Config {
 			"wait_timeout": strconv.Itoa(val.(int)),
 		}
 	})
+	if conf.ConnCompress == ConnCompressZlib {
+		// The go-sql-driver only implements the zlib wire-compression
+		// protocol. zstd would need connCompressSessionVars issued on every
+		// pooled connection, which nothing does, so ParseConnCompress
+		// rejects it.
+		driverCfg.Compress = true
+	}
 	return driverCfg
 }
 
@@ -309,7 +302,7 @@ func (*Config) DefineFlags(flags *pflag.FlagSet) {
 	storage.DefineFlags(flags)
 	flags.StringSliceP(flagDatabase, "B", nil, "Databases to dump")
 	flags.StringSliceP(flagTablesList, "T", nil, "Comma delimited table list to dump; must be qualified table names")
-	flags.StringP(flagHost, "h", "127.0.0.1", "The host to connect to")
+	flags.StringP(flagHost, "h", "127.0.0.1", "The host to connect to, accepts a hostname, IPv4 address, or bracketed IPv6 literal (e.g. [2001:db8::1]), optionally suffixed with :port (e.g. myhost:4000, [2001:db8::1]:4000)")
 	flags.StringP(flagUser, "u", "root", "Username with privileges to run the dump")
 	flags.IntP(flagPort, "P", 4000, "TCP/IP port to connect to")
 	flags.StringP(flagPassword, "p", "", "User password")
@@ -357,7 +350,12 @@ func (*Config) DefineFlags(flags *pflag.FlagSet) {
 	flags.Bool(flagTransactionalConsistency, true, "Only support transactional consistency")
 	_ = flags.MarkHidden(flagTransactionalConsistency)
 	flags.StringP(flagCompress, "c", "", "Compress output file type, support 'gzip', 'snappy', 'zstd', 'no-compression' now")
-	flags.String(flagCsvOutputDialect, "", "The dialect of output CSV file, support 'snowflake', 'redshift', 'bigquery' now")
+	flags.String(flagCsvOutputDialect, "", "The dialect of output CSV file, support 'snowflake', 'redshift', 'bigquery', 'databricks', 'duckdb', 'clickhouse', 'athena' now")
+	flags.String(flagConnCompress, "none", "Enable MySQL wire compression on the dump connections, support 'none', 'zlib' ('zstd' not supported yet)")
+	flags.String(flagRowsEstimateMethod, string(RowsEstimateMethodTableStatus), "The method used to estimate a table's row count for chunking, support 'table-status', 'explain', 'count', 'region'")
+	flags.String(flagJSONLBinaryFormat, "utf8", "The binary data format used when --filetype=jsonl, support 'utf8', 'hex', 'base64'")
+	flags.String(flagExtStorageHTTPProxy, "", "The proxy URL used by the external storage (S3/GCS/Azure) HTTP client")
+	flags.Duration(flagExtStorageRequestTimeout, 0, "The timeout for a single external storage HTTP request, 0 means no timeout")
 }
 
 // ParseFromFlags parses dumpling's export.Config from flags
@@ -372,6 +370,11 @@ func (conf *Config) ParseFromFlags(flags *pflag.FlagSet) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	hostOnly, hostPort, hasHostPort, err := splitHostPort(conf.Host)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.Host = hostOnly
 	conf.User, err = flags.GetString(flagUser)
 	if err != nil {
 		return errors.Trace(err)
@@ -380,6 +383,13 @@ func (conf *Config) ParseFromFlags(flags *pflag.FlagSet) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if hasHostPort && !flags.Changed(flagPort) {
+		// --host carried its own port (e.g. "myhost:4000" or
+		// "[2001:db8::1]:4000") and --port wasn't given explicitly, so let
+		// the host-embedded port win instead of silently falling back to
+		// --port's default.
+		conf.Port = hostPort
+	}
 	conf.Password, err = flags.GetString(flagPassword)
 	if err != nil {
 		return errors.Trace(err)
@@ -603,6 +613,42 @@ func (conf *Config) ParseFromFlags(flags *pflag.FlagSet) error {
 		return errors.Trace(err)
 	}
 
+	connCompress, err := flags.GetString(flagConnCompress)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.ConnCompress, err = ParseConnCompress(connCompress)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	rowsEstimateMethod, err := flags.GetString(flagRowsEstimateMethod)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.RowsEstimateMethod, err = ParseRowsEstimateMethod(rowsEstimateMethod)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	jsonlBinaryFormat, err := flags.GetString(flagJSONLBinaryFormat)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.JSONLBinaryFormat, err = ParseJSONLBinaryFormat(jsonlBinaryFormat)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	conf.ExtStorageHTTPProxy, err = flags.GetString(flagExtStorageHTTPProxy)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conf.ExtStorageRequestTimeout, err = flags.GetDuration(flagExtStorageRequestTimeout)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	for k, v := range params {
 		conf.SessionParams[k] = v
 	}
@@ -687,35 +733,6 @@ func ParseCompressType(compressType string) (storage.CompressType, error) {
 	}
 }
 
-// ParseOutputDialect parses output dialect string to Dialect
-func ParseOutputDialect(outputDialect string) (CSVDialect, error) {
-	switch outputDialect {
-	case "", "default":
-		return CSVDialectDefault, nil
-	case "snowflake":
-		return CSVDialectSnowflake, nil
-	case "redshift":
-		return CSVDialectRedshift, nil
-	case "bigquery":
-		return CSVDialectBigQuery, nil
-	default:
-		return CSVDialectDefault, errors.Errorf("unknown output dialect %s", outputDialect)
-	}
-}
-
-func (conf *Config) createExternalStorage(ctx context.Context) (storage.ExternalStorage, error) {
-	if conf.ExtStorage != nil {
-		return conf.ExtStorage, nil
-	}
-	b, err := storage.ParseBackend(conf.OutputDirPath, &conf.BackendOptions)
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-
-	// TODO: support setting httpClient with certification later
-	return storage.New(ctx, b, &storage.ExternalStorageOptions{})
-}
-
 const (
 	// UnspecifiedSize means the filesize/statement-size is unspecified
 	UnspecifiedSize = 0
@@ -738,13 +755,6 @@ const (
 	dumplingServiceSafePointPrefix = "dumpling"
 )
 
-var (
-	decodeRegionVersion    = semver.New("3.0.0")
-	gcSafePointVersion     = semver.New("4.0.0")
-	tableSampleVersion     = semver.New("5.0.0-nightly")
-	minNewTerminologyMySQL = semver.New("8.4.0") // first MySQL version to no longer support MASTER/SLAVE/etc
-)
-
 func adjustConfig(conf *Config, fns ...func(*Config) error) error {
 	for _, f := range fns {
 		err := f(conf)
@@ -791,21 +801,9 @@ func adjustFileFormat(conf *Config) error {
 			return errors.Errorf("unsupported config.FileType '%s' when we specify --sql, please unset --filetype or set it to 'csv'", conf.FileType)
 		}
 	case FileFormatCSVString:
+	case FileFormatJSONLString:
 	default:
 		return errors.Errorf("unknown config.FileType '%s'", conf.FileType)
 	}
 	return nil
 }
-
-func matchMysqlBugversion(info version.ServerInfo) bool {
-	// if 8.0.3 <= mysql8 version < 8.0.23
-	// FLUSH TABLES WITH READ LOCK could block other sessions from executing SHOW TABLE STATUS.
-	// see more in https://dev.mysql.com/doc/relnotes/mysql/8.0/en/news-8-0-23.html
-	if info.ServerType != version.ServerTypeMySQL {
-		return false
-	}
-	currentVersion := info.ServerVersion
-	bugVersionStart := semver.New("8.0.2")
-	bugVersionEnd := semver.New("8.0.23")
-	return bugVersionStart.LessThan(*currentVersion) && currentVersion.LessThan(*bugVersionEnd)
-}
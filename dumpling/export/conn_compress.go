@@ -0,0 +1,58 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import "github.com/pingcap/errors"
+
+// ConnCompress is the MySQL client/server wire compression algorithm used for
+// the dump session's connections, independent of the output file compression
+// controlled by --compress.
+type ConnCompress string
+
+const (
+	// ConnCompressNone disables wire compression (the default).
+	ConnCompressNone ConnCompress = "none"
+	// ConnCompressZlib enables zlib wire compression, supported by every
+	// MySQL/MariaDB/TiDB version dumpling targets.
+	ConnCompressZlib ConnCompress = "zlib"
+	// ConnCompressZstd would enable zstd wire compression, only supported by
+	// MySQL 8.0.18+ and recent TiDB. Not wired into GetDriverConfig's caller
+	// yet, so ParseConnCompress rejects it rather than accept it as a no-op;
+	// see connCompressSessionVars.
+	ConnCompressZstd ConnCompress = "zstd"
+)
+
+// ParseConnCompress parses the --conn-compress flag value into a ConnCompress.
+// ConnCompressZstd is rejected here rather than accepted silently: nothing
+// actually issues connCompressSessionVars against the dump connections yet,
+// so letting it through would make --conn-compress=zstd a silent no-op.
+func ParseConnCompress(s string) (ConnCompress, error) {
+	switch ConnCompress(s) {
+	case "", ConnCompressNone:
+		return ConnCompressNone, nil
+	case ConnCompressZlib:
+		return ConnCompressZlib, nil
+	case ConnCompressZstd:
+		return ConnCompressNone, errors.Errorf("conn-compress=%s is not supported yet", ConnCompressZstd)
+	default:
+		return ConnCompressNone, errors.Errorf("unknown conn-compress %s", s)
+	}
+}
+
+// connCompressSessionVars returns the SET SESSION statements needed to
+// negotiate zstd wire compression with the server, since the driver's DSN
+// option only covers zlib. It returns nil for ConnCompressNone/ConnCompressZlib.
+//
+// Nothing calls this yet: the dump connection pool is opened elsewhere, and
+// wiring this in means executing these statements against every pooled
+// connection right after it's opened. Until that call site exists,
+// ParseConnCompress rejects ConnCompressZstd so --conn-compress=zstd fails
+// fast instead of silently running uncompressed.
+func connCompressSessionVars(compress ConnCompress) []string {
+	if compress != ConnCompressZstd {
+		return nil
+	}
+	return []string{
+		"SET SESSION protocol_compression_algorithms='zstd,uncompressed'",
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// CSVDialect describes how CSV output should be rendered for a particular
+// import target. Unlike the old hardcoded enum, dialects are registered by
+// name via RegisterCSVDialect, so downstream users can plug in a warehouse
+// dumpling doesn't know about without patching this package.
+type CSVDialect struct {
+	// Name identifies the dialect, e.g. "snowflake". Matches the value
+	// accepted by --csv-output-dialect.
+	Name string
+	// NullSentinel is written in place of SQL NULL.
+	NullSentinel string
+	// BinaryFormat controls how binary column values are rendered.
+	BinaryFormat BinaryFormat
+	// BoolTrueLiteral/BoolFalseLiteral render BOOLEAN columns. Empty means
+	// render as the numeric 1/0, which most dialects expect.
+	BoolTrueLiteral  string
+	BoolFalseLiteral string
+	// TimestampFormat is a Go time layout used for TIMESTAMP/DATETIME columns.
+	TimestampFormat string
+	// DoubledQuoteEscape selects doubled-quote escaping (`""`) for quotes
+	// embedded in a field; when false, backslash escaping is used instead.
+	DoubledQuoteEscape bool
+	// RecordTerminator overrides Config.CsvLineTerminator when non-empty,
+	// for dialects that require a specific line ending.
+	RecordTerminator string
+	// IncludeHeaderRow overrides Config.NoHeader when true, for dialects
+	// that always expect a header (e.g. ClickHouse's CSVWithNames).
+	IncludeHeaderRow bool
+}
+
+var csvDialectRegistry = map[string]*CSVDialect{}
+
+// RegisterCSVDialect registers a CSVDialect under name (case-insensitive), so
+// it becomes a valid --csv-output-dialect value. Re-registering an existing
+// name overwrites it.
+func RegisterCSVDialect(name string, d *CSVDialect) {
+	csvDialectRegistry[strings.ToLower(name)] = d
+}
+
+func init() {
+	RegisterCSVDialect("default", &CSVDialect{
+		Name:               "default",
+		NullSentinel:       `\N`,
+		BinaryFormat:       BinaryFormatUTF8,
+		DoubledQuoteEscape: false,
+	})
+	RegisterCSVDialect("snowflake", &CSVDialect{
+		Name:               "snowflake",
+		NullSentinel:       `\N`,
+		BinaryFormat:       BinaryFormatHEX,
+		DoubledQuoteEscape: true,
+	})
+	RegisterCSVDialect("redshift", &CSVDialect{
+		Name:               "redshift",
+		NullSentinel:       "",
+		BinaryFormat:       BinaryFormatHEX,
+		DoubledQuoteEscape: true,
+	})
+	RegisterCSVDialect("bigquery", &CSVDialect{
+		Name:               "bigquery",
+		NullSentinel:       "",
+		BinaryFormat:       BinaryFormatBase64,
+		DoubledQuoteEscape: true,
+	})
+	RegisterCSVDialect("databricks", &CSVDialect{
+		Name:               "databricks",
+		NullSentinel:       `\N`,
+		BinaryFormat:       BinaryFormatBase64,
+		DoubledQuoteEscape: true,
+		TimestampFormat:    "2006-01-02 15:04:05.000000",
+	})
+	RegisterCSVDialect("duckdb", &CSVDialect{
+		Name:               "duckdb",
+		NullSentinel:       "",
+		BinaryFormat:       BinaryFormatHEX,
+		BoolTrueLiteral:    "true",
+		BoolFalseLiteral:   "false",
+		DoubledQuoteEscape: true,
+	})
+	RegisterCSVDialect("clickhouse", &CSVDialect{
+		Name:               "clickhouse",
+		NullSentinel:       `\N`,
+		BinaryFormat:       BinaryFormatHEX,
+		DoubledQuoteEscape: true,
+		IncludeHeaderRow:   true,
+	})
+	RegisterCSVDialect("athena", &CSVDialect{
+		Name:               "athena",
+		NullSentinel:       "",
+		BinaryFormat:       BinaryFormatHEX,
+		DoubledQuoteEscape: true,
+	})
+	// Registration above always includes "default", so this lookup never
+	// leaves CSVDialectDefault nil.
+	CSVDialectDefault = csvDialectRegistry["default"]
+}
+
+// CSVDialectDefault is the default dialect, which is MySQL/MariaDB/TiDB etc.
+// It's resolved in init(), after RegisterCSVDialect populates the registry.
+var CSVDialectDefault *CSVDialect
+
+// ParseOutputDialect parses an output dialect name into its registered
+// CSVDialect, looking it up in the registry populated by RegisterCSVDialect.
+func ParseOutputDialect(outputDialect string) (*CSVDialect, error) {
+	name := outputDialect
+	if name == "" {
+		name = "default"
+	}
+	d, ok := csvDialectRegistry[strings.ToLower(name)]
+	if !ok {
+		return CSVDialectDefault, errors.Errorf("unknown output dialect %s", outputDialect)
+	}
+	return d, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/errors"
+)
+
+// ErrorClass classifies a MySQL error by how the dump loop should react to
+// it, instead of callers comparing error strings themselves.
+type ErrorClass int
+
+const (
+	// ErrorClassUnknown covers errors that don't unwrap to a *mysql.MySQLError,
+	// or whose error number isn't in the table below. Callers should treat
+	// these as fatal unless they have more specific handling.
+	ErrorClassUnknown ErrorClass = iota
+	// ErrorClassRetryable covers transient errors, e.g. connection resets or
+	// lock wait timeouts, where retrying the same statement can succeed.
+	ErrorClassRetryable
+	// ErrorClassSkippable covers errors where the object being dumped is
+	// gone, so the dump loop can skip it and move on to the next one.
+	ErrorClassSkippable
+	// ErrorClassFatal covers errors that won't be resolved by retrying or
+	// skipping, e.g. authentication failures.
+	ErrorClassFatal
+)
+
+// retryableMySQLErrors are ER_ errors that are transient: the same statement
+// can succeed on retry once the underlying condition clears.
+var retryableMySQLErrors = map[uint16]struct{}{
+	1053: {}, // ER_SERVER_SHUTDOWN
+	1205: {}, // ER_LOCK_WAIT_TIMEOUT
+	1213: {}, // ER_LOCK_DEADLOCK
+	1290: {}, // ER_OPTION_PREVENTS_STATEMENT (e.g. read-only during failover)
+	1317: {}, // ER_QUERY_INTERRUPTED
+	2006: {}, // CR_SERVER_GONE_ERROR
+	2013: {}, // CR_SERVER_LOST
+}
+
+// skippableMySQLErrors indicate the object being dumped disappeared mid-dump,
+// so the dump loop can skip it rather than aborting the whole dump.
+var skippableMySQLErrors = map[uint16]struct{}{
+	1146: {}, // ER_NO_SUCH_TABLE
+	1051: {}, // ER_BAD_TABLE_ERROR
+}
+
+// fatalMySQLErrors can't be resolved by retrying or skipping.
+var fatalMySQLErrors = map[uint16]struct{}{
+	1044: {}, // ER_DBACCESS_DENIED_ERROR
+	1045: {}, // ER_ACCESS_DENIED_ERROR
+	1142: {}, // ER_TABLEACCESS_DENIED_ERROR
+}
+
+// ClassifyMySQLError unwraps err to a *mysql.MySQLError and classifies it by
+// MySQLError.Number, instead of callers comparing error message strings.
+// Errors that don't unwrap to a *mysql.MySQLError are ErrorClassUnknown.
+func ClassifyMySQLError(err error) ErrorClass {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return ErrorClassUnknown
+	}
+	if _, ok := retryableMySQLErrors[mysqlErr.Number]; ok {
+		return ErrorClassRetryable
+	}
+	if _, ok := skippableMySQLErrors[mysqlErr.Number]; ok {
+		return ErrorClassSkippable
+	}
+	if _, ok := fatalMySQLErrors[mysqlErr.Number]; ok {
+		return ErrorClassFatal
+	}
+	return ErrorClassUnknown
+}
+
+// ErrorPolicy controls how the dump loop reacts to errors classified by
+// ClassifyMySQLError, instead of hardcoding retry/skip behavior at every
+// call site.
+type ErrorPolicy struct {
+	// MaxRetries bounds how many times a retryable error is retried before
+	// it's treated as fatal.
+	MaxRetries int
+	// Backoff is the delay before each retry.
+	Backoff time.Duration
+	// OnSkip, if set, is invoked whenever a skippable error causes the dump
+	// loop to move on, so callers can log or account for it.
+	OnSkip func(err error)
+}
+
+// DefaultErrorPolicy is a conservative default: a handful of quick retries,
+// and skips logged but otherwise silent.
+func DefaultErrorPolicy() ErrorPolicy {
+	return ErrorPolicy{
+		MaxRetries: 3,
+		Backoff:    time.Second,
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/pingcap/errors"
+)
+
+// normalizeHost strips the brackets from a bracketed IPv6 literal (e.g.
+// "[2001:db8::1]" -> "2001:db8::1"), so Host can be fed uniformly into
+// net.JoinHostPort everywhere it's rendered into a DSN or URL. Plain
+// hostnames and IPv4 addresses are returned unchanged.
+func normalizeHost(host string) string {
+	if len(host) >= 2 && host[0] == '[' && host[len(host)-1] == ']' {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+// splitHostPort separates an optional ":port" suffix out of a --host value,
+// so a user who pastes a combined address (e.g. "myhost:4000" or the
+// bracketed IPv6 form "[2001:db8::1]:4000") doesn't have to split it by
+// hand before also passing --port. hostOnly is always normalized the same
+// way normalizeHost would. hasPort is false for a bare hostname/IPv4
+// address, a bracketed IPv6 literal with no port (returned without its
+// brackets), or an unbracketed IPv6 literal — net.SplitHostPort's own "too
+// many colons" error is what tells an unbracketed IPv6 address apart from
+// an actual host:port pair here.
+func splitHostPort(host string) (hostOnly string, port int, hasPort bool, err error) {
+	h, p, splitErr := net.SplitHostPort(host)
+	if splitErr != nil {
+		return normalizeHost(host), 0, false, nil
+	}
+	portNum, convErr := strconv.Atoi(p)
+	if convErr != nil {
+		return "", 0, false, errors.Errorf("invalid port %q in host %q", p, host)
+	}
+	return h, portNum, true, nil
+}
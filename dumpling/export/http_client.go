@@ -0,0 +1,52 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/br/pkg/storage"
+)
+
+func (conf *Config) createExternalStorage(ctx context.Context) (storage.ExternalStorage, error) {
+	if conf.ExtStorage != nil {
+		return conf.ExtStorage, nil
+	}
+	b, err := storage.ParseBackend(conf.OutputDirPath, &conf.BackendOptions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	httpClient, err := conf.buildExtStorageHTTPClient()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return storage.New(ctx, b, &storage.ExternalStorageOptions{
+		HTTPClient: httpClient,
+	})
+}
+
+// buildExtStorageHTTPClient builds the *http.Client used to talk to the
+// external storage backend, reusing the same CA/cert bundle as the MySQL
+// connection (conf.Security.TLS) and honoring ExtStorageHTTPProxy/
+// ExtStorageRequestTimeout.
+func (conf *Config) buildExtStorageHTTPClient() (*http.Client, error) {
+	transport := &http.Transport{}
+	if conf.Security.TLS != nil {
+		transport.TLSClientConfig = conf.Security.TLS.Clone()
+	}
+	if conf.ExtStorageHTTPProxy != "" {
+		proxyURL, err := url.Parse(conf.ExtStorageHTTPProxy)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   conf.ExtStorageRequestTimeout,
+	}, nil
+}
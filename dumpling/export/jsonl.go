@@ -0,0 +1,37 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import "github.com/pingcap/errors"
+
+// BinaryFormat is the format of binary data
+// Three standard formats are supported: UTF8, HEX and Base64 now.
+type BinaryFormat int
+
+const (
+	// BinaryFormatUTF8 is the default format, format binary data as UTF8 string
+	BinaryFormatUTF8 BinaryFormat = iota
+	// BinaryFormatHEX format binary data as HEX string, e.g. 12ABCD
+	BinaryFormatHEX
+	// BinaryFormatBase64 format binary data as Base64 string, e.g. 123qwer==
+	BinaryFormatBase64
+)
+
+// FileFormatJSONLString is the `--filetype` value for newline-delimited JSON
+// output (one JSON object per row, keys = column names), alongside the
+// existing FileFormatSQLTextString and FileFormatCSVString.
+const FileFormatJSONLString = "jsonl"
+
+// ParseJSONLBinaryFormat parses the --jsonl-binary-format flag value.
+func ParseJSONLBinaryFormat(s string) (BinaryFormat, error) {
+	switch s {
+	case "", "utf8":
+		return BinaryFormatUTF8, nil
+	case "hex":
+		return BinaryFormatHEX, nil
+	case "base64":
+		return BinaryFormatBase64, nil
+	default:
+		return BinaryFormatUTF8, errors.Errorf("unknown jsonl-binary-format %s", s)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import (
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/pingcap/tidb/br/pkg/version"
+)
+
+// ServerQuirk is a bitmask of server-version-specific behavior that the
+// dump/consistency code paths need to work around or adapt to. It replaces a
+// set of ad-hoc *semver.Version globals and one-off matchXxx functions with a
+// single table keyed by (ServerType, version range), so adding support for a
+// new distribution or quirky version doesn't mean adding another global.
+type ServerQuirk uint32
+
+const (
+	// QuirkAvoidFlushTablesWithReadLock marks servers where FLUSH TABLES WITH
+	// READ LOCK either isn't permitted or can block unrelated sessions, and a
+	// different mechanism must be used to get a consistent snapshot.
+	QuirkAvoidFlushTablesWithReadLock ServerQuirk = 1 << iota
+	// QuirkUseBackupLockInsteadOfFTWRL marks servers that support a
+	// lighter-weight backup lock (e.g. MariaDB's BACKUP STAGE) in place of
+	// FLUSH TABLES WITH READ LOCK.
+	QuirkUseBackupLockInsteadOfFTWRL
+	// QuirkNoConsistentSnapshotInTxn marks servers where a single
+	// START TRANSACTION WITH CONSISTENT SNAPSHOT cannot be relied on to pin a
+	// consistent view of all tables, and a vendor-specific mechanism (e.g.
+	// Aurora's aurora_replica_read_consistency) must be used instead.
+	QuirkNoConsistentSnapshotInTxn
+	// QuirkTableSampleUnsupported marks servers that don't understand
+	// TABLESAMPLE, so region/row-count estimation must fall back to another
+	// strategy.
+	QuirkTableSampleUnsupported
+	// QuirkNoRegionDecode marks servers that don't expose the region
+	// boundaries dumpling decodes for TiKV-aware chunking.
+	QuirkNoRegionDecode
+	// QuirkNoGCSafePoint marks servers without a GC safe point to register
+	// against, so dumpling can't protect its long-running snapshot from GC.
+	QuirkNoGCSafePoint
+	// QuirkNoBinlogPosSQL marks servers where collecting a binlog/GTID
+	// position via SQL doesn't apply, e.g. TiDB, and the step should be
+	// skipped instead of attempted and failed.
+	QuirkNoBinlogPosSQL
+	// QuirkNewTerminology marks servers new enough to have dropped the
+	// MASTER/SLAVE terminology from replication-related statements and
+	// status variables.
+	QuirkNewTerminology
+)
+
+// serverQuirkRange associates a ServerQuirk set with every ServerVersion in
+// [min, max) of the given ServerType. A nil min/max means unbounded on that
+// side. auroraOnly further restricts the range to servers matchAurora
+// recognizes as Aurora MySQL, since Aurora reports itself as an ordinary
+// MySQL ServerType and version.
+type serverQuirkRange struct {
+	serverType version.ServerType
+	min, max   *semver.Version
+	auroraOnly bool
+	quirks     ServerQuirk
+}
+
+var serverQuirkTable = []serverQuirkRange{
+	{
+		// if 8.0.3 <= mysql8 version < 8.0.23
+		// FLUSH TABLES WITH READ LOCK could block other sessions from executing SHOW TABLE STATUS.
+		// see more in https://dev.mysql.com/doc/relnotes/mysql/8.0/en/news-8-0-23.html
+		serverType: version.ServerTypeMySQL,
+		min:        semver.New("8.0.3"),
+		max:        semver.New("8.0.23"),
+		quirks:     QuirkAvoidFlushTablesWithReadLock,
+	},
+	{
+		// first MySQL version to no longer support MASTER/SLAVE/etc.
+		serverType: version.ServerTypeMySQL,
+		min:        semver.New("8.4.0"),
+		quirks:     QuirkNewTerminology,
+	},
+	{
+		// Aurora MySQL doesn't permit FLUSH TABLES WITH READ LOCK and instead
+		// relies on aurora_replica_read_consistency to get a consistent view
+		// without a single START TRANSACTION WITH CONSISTENT SNAPSHOT.
+		serverType: version.ServerTypeMySQL,
+		auroraOnly: true,
+		quirks:     QuirkAvoidFlushTablesWithReadLock | QuirkNoConsistentSnapshotInTxn,
+	},
+	{
+		// MariaDB 10.4+ can use BACKUP STAGE in place of FTWRL.
+		serverType: version.ServerTypeMariaDB,
+		min:        semver.New("10.4.0"),
+		quirks:     QuirkUseBackupLockInsteadOfFTWRL,
+	},
+	{
+		// TiDB has no binlog/GTID position to collect via SQL.
+		serverType: version.ServerTypeTiDB,
+		quirks:     QuirkNoBinlogPosSQL,
+	},
+	{
+		serverType: version.ServerTypeTiDB,
+		max:        semver.New("3.0.0"),
+		quirks:     QuirkNoRegionDecode,
+	},
+	{
+		serverType: version.ServerTypeTiDB,
+		max:        semver.New("4.0.0"),
+		quirks:     QuirkNoGCSafePoint,
+	},
+	{
+		serverType: version.ServerTypeTiDB,
+		max:        semver.New("5.0.0-nightly"),
+		quirks:     QuirkTableSampleUnsupported,
+	},
+}
+
+// matchAurora reports whether info looks like Aurora MySQL. Aurora reports
+// its ServerType as plain MySQL, but stamps its build metadata onto the
+// semver (e.g. "8.0.28-aurora-..."), which is the only signal ServerInfo
+// carries for it.
+func matchAurora(info version.ServerInfo) bool {
+	return info.ServerType == version.ServerTypeMySQL &&
+		info.ServerVersion != nil &&
+		strings.Contains(strings.ToLower(info.ServerVersion.Metadata), "aurora")
+}
+
+// ServerQuirks returns the set of ServerQuirks that apply to info, by
+// scanning serverQuirkTable for every range that matches its ServerType,
+// version, and Aurora-ness.
+func ServerQuirks(info version.ServerInfo) ServerQuirk {
+	if info.ServerVersion == nil {
+		return 0
+	}
+	isAurora := matchAurora(info)
+	var quirks ServerQuirk
+	for _, r := range serverQuirkTable {
+		if r.serverType != info.ServerType {
+			continue
+		}
+		if r.auroraOnly && !isAurora {
+			continue
+		}
+		if r.min != nil && info.ServerVersion.LessThan(*r.min) {
+			continue
+		}
+		if r.max != nil && !info.ServerVersion.LessThan(*r.max) {
+			continue
+		}
+		quirks |= r.quirks
+	}
+	return quirks
+}
+
+// HasServerQuirk reports whether info matches the quirk q.
+func HasServerQuirk(info version.ServerInfo, q ServerQuirk) bool {
+	return ServerQuirks(info)&q != 0
+}
+
+// matchMysqlBugversion reports whether info is affected by the MySQL 8.0
+// FLUSH TABLES WITH READ LOCK / SHOW TABLE STATUS bug. It's kept as a thin
+// wrapper over HasServerQuirk for existing callers; new code should consult
+// ServerQuirks/HasServerQuirk directly.
+func matchMysqlBugversion(info version.ServerInfo) bool {
+	return HasServerQuirk(info, QuirkAvoidFlushTablesWithReadLock)
+}
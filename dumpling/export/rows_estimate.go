@@ -0,0 +1,41 @@
+// Copyright 2020 PingCAP, Inc. Licensed under Apache-2.0.
+
+package export
+
+import "github.com/pingcap/errors"
+
+// RowsEstimateMethod is the strategy used to estimate a table's row count
+// when deciding how to split it into --rows-sized chunks.
+type RowsEstimateMethod string
+
+const (
+	// RowsEstimateMethodTableStatus reads information_schema.tables.table_rows,
+	// which is fast but can be stale or coarse.
+	RowsEstimateMethodTableStatus RowsEstimateMethod = "table-status"
+	// RowsEstimateMethodExplain runs EXPLAIN SELECT * FROM t and reads the
+	// planner's `rows` estimate, which is usually more accurate for
+	// sharded/partitioned tables.
+	RowsEstimateMethodExplain RowsEstimateMethod = "explain"
+	// RowsEstimateMethodCount runs SELECT COUNT(*) under the dump snapshot.
+	// It is exact but expensive on large tables.
+	RowsEstimateMethodCount RowsEstimateMethod = "count"
+	// RowsEstimateMethodRegion keeps dumpling's original TiDB-specific
+	// behavior of splitting by region boundaries.
+	RowsEstimateMethodRegion RowsEstimateMethod = "region"
+)
+
+// ParseRowsEstimateMethod parses the --rows-estimate-method flag value.
+func ParseRowsEstimateMethod(s string) (RowsEstimateMethod, error) {
+	switch RowsEstimateMethod(s) {
+	case "", RowsEstimateMethodTableStatus:
+		return RowsEstimateMethodTableStatus, nil
+	case RowsEstimateMethodExplain:
+		return RowsEstimateMethodExplain, nil
+	case RowsEstimateMethodCount:
+		return RowsEstimateMethodCount, nil
+	case RowsEstimateMethodRegion:
+		return RowsEstimateMethodRegion, nil
+	default:
+		return "", errors.Errorf("unknown rows-estimate-method %s", s)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// defaultMemoryLimitRatio is the fallback for tidb_ddl_ingest_memory_limit_ratio
+// when the system variable hasn't been wired in yet.
+const defaultMemoryLimitRatio = 0.8
+
+// SystemMemUsage is a snapshot of the process and system memory pressure, as
+// observed by a MemoryChecker.
+type SystemMemUsage struct {
+	// ProcessRSS is the resident set size of the current process.
+	ProcessRSS uint64
+	// SystemTotal is the total physical memory of the machine/container.
+	SystemTotal uint64
+	// SystemAvailable is the memory the OS reports as available for new
+	// allocations, which already accounts for reclaimable caches.
+	SystemAvailable uint64
+}
+
+// MemoryChecker gates memory admission on signals that memRootImpl's own
+// bookkeeping cannot see, such as large cgo/pebble/SST allocations. It
+// complements the accounted quota in CheckConsume/BlockingConsume instead of
+// replacing it.
+type MemoryChecker interface {
+	// Refresh resamples the underlying OS/process counters and returns the
+	// new snapshot.
+	Refresh() (SystemMemUsage, error)
+	// Allowed reports whether `extra` more bytes can be admitted without
+	// exceeding the pressure threshold, using the last snapshot taken by
+	// Refresh.
+	Allowed(extra int64) (bool, SystemMemUsage)
+}
+
+// osMemoryChecker is the default MemoryChecker, backed by gopsutil/mem for
+// system-wide memory and gopsutil/process for this process' RSS.
+type osMemoryChecker struct {
+	// ratio is tidb_ddl_ingest_memory_limit_ratio: the fraction of system
+	// memory the ingest backend is allowed to occupy.
+	ratio float64
+
+	mu   sync.RWMutex
+	last SystemMemUsage
+}
+
+// NewOSMemoryChecker creates a MemoryChecker that rejects admission once the
+// process RSS would exceed `ratio` of total system memory.
+func NewOSMemoryChecker(ratio float64) *osMemoryChecker {
+	if ratio <= 0 || ratio > 1 {
+		ratio = defaultMemoryLimitRatio
+	}
+	return &osMemoryChecker{ratio: ratio}
+}
+
+// Refresh implements MemoryChecker.
+func (c *osMemoryChecker) Refresh() (SystemMemUsage, error) {
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return SystemMemUsage{}, err
+	}
+	usage := SystemMemUsage{SystemTotal: vm.Total, SystemAvailable: vm.Available}
+	if rss := processRSS(); rss > 0 {
+		usage.ProcessRSS = rss
+	} else {
+		// Fall back to the Go runtime's view of its own footprint when the
+		// process' RSS cannot be read, e.g. on platforms gopsutil doesn't
+		// support.
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		usage.ProcessRSS = ms.Sys
+	}
+
+	c.mu.Lock()
+	c.last = usage
+	c.mu.Unlock()
+	return usage, nil
+}
+
+// Allowed implements MemoryChecker.
+func (c *osMemoryChecker) Allowed(extra int64) (bool, SystemMemUsage) {
+	c.mu.RLock()
+	usage := c.last
+	c.mu.RUnlock()
+	if usage.SystemTotal == 0 {
+		// No sample has been taken yet; don't block on an unknown quantity.
+		return true, usage
+	}
+	limit := uint64(float64(usage.SystemTotal) * c.ratio)
+	return usage.ProcessRSS+uint64(extra) <= limit, usage
+}
+
+func processRSS() uint64 {
+	p, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return 0
+	}
+	info, err := p.MemoryInfo()
+	if err != nil || info == nil {
+		return 0
+	}
+	return info.RSS
+}
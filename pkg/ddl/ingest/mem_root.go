@@ -15,20 +15,33 @@
 package ingest
 
 import (
+	"context"
 	"sync"
+	"time"
 	"unsafe"
 )
 
 // MemRoot is used to track the memory usage for the lightning backfill process.
-// TODO(lance6716): change API to prevent TOCTOU.
 type MemRoot interface {
 	Consume(size int64)
 	Release(size int64)
 	CheckConsume(size int64) bool
+	// TryConsume atomically tests whether size can be consumed without exceeding
+	// the quota and, if so, consumes it in the same critical section. It should
+	// be preferred over a CheckConsume+Consume pair, which is subject to TOCTOU.
+	TryConsume(size int64) bool
+	// BlockingConsume blocks the caller until size can be consumed without
+	// exceeding the quota, or ctx is done. Waiters are not admitted in any
+	// defined order.
+	BlockingConsume(ctx context.Context, size int64) error
 	// ConsumeWithTag consumes memory with a tag. The main difference between
 	// ConsumeWithTag and Consume is that if the memory is updated afterward, caller
 	// can use ReleaseWithTag then ConsumeWithTag to update the memory usage.
 	ConsumeWithTag(tag string, size int64)
+	// TryConsumeWithTag is the tagged counterpart of TryConsume.
+	TryConsumeWithTag(tag string, size int64) bool
+	// BlockingConsumeWithTag is the tagged counterpart of BlockingConsume.
+	BlockingConsumeWithTag(ctx context.Context, tag string, size int64) error
 	ReleaseWithTag(tag string)
 
 	SetMaxMemoryQuota(quota int64)
@@ -36,6 +49,10 @@ type MemRoot interface {
 	CurrentUsage() int64
 	CurrentUsageWithTag(tag string) int64
 	RefreshConsumption()
+	// SystemUsage returns the last OS-level memory snapshot observed by the
+	// MemRoot's MemoryChecker, so callers can throttle on real memory
+	// pressure instead of only the accounted budget.
+	SystemUsage() SystemMemUsage
 }
 
 var (
@@ -56,22 +73,98 @@ type memRootImpl struct {
 	currUsage  int64
 	structSize map[string]int64
 	mu         sync.RWMutex
+	// cond is signaled every time currUsage or maxLimit could have shrunk, so
+	// that blocked waiters get a chance to re-check the budget. Every signal
+	// is a Broadcast: all waiters wake and re-check, and whichever takes mu
+	// first is admitted first.
+	cond *sync.Cond
+	// checker gates admission on OS-level memory pressure, in addition to the
+	// accounted currUsage/maxLimit budget above.
+	checker      MemoryChecker
+	refreshStopc chan struct{}
+
+	// children holds the fair-share child MemRoots handed out to concurrent
+	// DDL jobs, keyed by job tag. See NewChildMemRoot.
+	children map[string]*childMemRoot
 }
 
 // NewMemRootImpl creates a new memRootImpl.
 func NewMemRootImpl(maxQuota int64) *memRootImpl {
-	return &memRootImpl{
+	m := &memRootImpl{
 		maxLimit:   maxQuota,
 		currUsage:  0,
 		structSize: make(map[string]int64, 10),
+		checker:    NewOSMemoryChecker(defaultMemoryLimitRatio),
+	}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// SetMemoryChecker overrides the MemRoot's MemoryChecker, e.g. to inject a
+// fake one in tests or to apply the tidb_ddl_ingest_memory_limit_ratio
+// session variable.
+func (m *memRootImpl) SetMemoryChecker(checker MemoryChecker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checker = checker
+}
+
+// StartPressureMonitor samples the MemoryChecker on a ticker until
+// StopPressureMonitor is called, so SystemUsage and the pressure gate in CheckConsume/BlockingConsume
+// reflect memory used outside of the accounted quota.
+func (m *memRootImpl) StartPressureMonitor(interval time.Duration) {
+	m.mu.Lock()
+	if m.refreshStopc != nil {
+		m.mu.Unlock()
+		return
+	}
+	stopc := make(chan struct{})
+	m.refreshStopc = stopc
+	m.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.RefreshConsumption()
+			case <-stopc:
+				return
+			}
+		}
+	}()
+}
+
+// StopPressureMonitor stops a previously started StartPressureMonitor.
+func (m *memRootImpl) StopPressureMonitor() {
+	m.mu.Lock()
+	stopc := m.refreshStopc
+	m.refreshStopc = nil
+	m.mu.Unlock()
+	if stopc != nil {
+		close(stopc)
 	}
 }
 
+// SystemUsage implements MemRoot.
+func (m *memRootImpl) SystemUsage() SystemMemUsage {
+	m.mu.RLock()
+	checker := m.checker
+	m.mu.RUnlock()
+	if checker == nil {
+		return SystemMemUsage{}
+	}
+	_, usage := checker.Allowed(0)
+	return usage
+}
+
 // SetMaxMemoryQuota implements MemRoot.
 func (m *memRootImpl) SetMaxMemoryQuota(maxQuota int64) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.maxLimit = maxQuota
+	m.mu.Unlock()
+	m.cond.Broadcast()
 }
 
 // MaxMemoryQuota implements MemRoot.
@@ -105,8 +198,9 @@ func (m *memRootImpl) Consume(size int64) {
 // Release implements MemRoot.
 func (m *memRootImpl) Release(size int64) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.currUsage -= size
+	m.mu.Unlock()
+	m.cond.Broadcast()
 }
 
 // ConsumeWithTag implements MemRoot.
@@ -125,18 +219,108 @@ func (m *memRootImpl) ConsumeWithTag(tag string, size int64) {
 func (m *memRootImpl) CheckConsume(size int64) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.currUsage+size <= m.maxLimit
+	return m.canAdmitLocked(size)
+}
+
+// canAdmitLocked reports whether size can be admitted under both the
+// accounted budget and the OS-level pressure gate. The caller must hold at
+// least a read lock.
+func (m *memRootImpl) canAdmitLocked(size int64) bool {
+	if m.currUsage+size > m.maxLimit {
+		return false
+	}
+	if m.checker == nil {
+		return true
+	}
+	allowed, _ := m.checker.Allowed(size)
+	return allowed
+}
+
+// TryConsume implements MemRoot.
+func (m *memRootImpl) TryConsume(size int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.canAdmitLocked(size) {
+		return false
+	}
+	m.currUsage += size
+	return true
+}
+
+// TryConsumeWithTag implements MemRoot.
+func (m *memRootImpl) TryConsumeWithTag(tag string, size int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.canAdmitLocked(size) {
+		return false
+	}
+	m.currUsage += size
+	m.structSize[tag] += size
+	return true
+}
+
+// BlockingConsume implements MemRoot.
+func (m *memRootImpl) BlockingConsume(ctx context.Context, size int64) error {
+	return m.blockingConsume(ctx, "", size)
+}
+
+// BlockingConsumeWithTag implements MemRoot.
+func (m *memRootImpl) BlockingConsumeWithTag(ctx context.Context, tag string, size int64) error {
+	return m.blockingConsume(ctx, tag, size)
+}
+
+// blockingConsume waits until size can be admitted under maxLimit, then
+// consumes it atomically. When ctx is canceled while waiting, the waiter is
+// woken by a background goroutine that watches ctx.Done and broadcasts, so it
+// can re-check and return ctx.Err() instead of blocking forever.
+func (m *memRootImpl) blockingConsume(ctx context.Context, tag string, size int64) error {
+	stopWatch := context.AfterFunc(ctx, func() {
+		// Hold mu so the broadcast can't land between a waiter's ctx.Err()
+		// check and its cond.Wait, where it would be lost.
+		m.mu.Lock()
+		m.cond.Broadcast()
+		m.mu.Unlock()
+	})
+	defer stopWatch()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for !m.canAdmitLocked(size) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m.cond.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.currUsage += size
+	if tag != "" {
+		m.structSize[tag] += size
+	}
+	return nil
 }
 
 // ReleaseWithTag implements MemRoot.
 func (m *memRootImpl) ReleaseWithTag(tag string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.currUsage -= m.structSize[tag]
 	delete(m.structSize, tag)
+	m.mu.Unlock()
+	m.cond.Broadcast()
 }
 
 // RefreshConsumption implements MemRoot.
-func (*memRootImpl) RefreshConsumption() {
+func (m *memRootImpl) RefreshConsumption() {
 	// TODO(tagnenta): find a better solution that don't rely on backendCtxMgr.
+	m.mu.RLock()
+	checker := m.checker
+	m.mu.RUnlock()
+	if checker == nil {
+		return
+	}
+	if _, err := checker.Refresh(); err == nil {
+		// A drop in system pressure may unblock BlockingConsume waiters.
+		m.cond.Broadcast()
+	}
 }
@@ -0,0 +1,310 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/pingcap/errors"
+)
+
+// numCountersPerTag is ristretto's recommended 10x multiplier between
+// NumCounters and the expected number of distinct keys, used to keep the
+// TinyLFU admission sketch accurate.
+const numCountersPerTag = 10
+
+// CacheMetrics reports the hit/miss/eviction counters of a cachingMemRoot, for
+// exporting to Prometheus.
+type CacheMetrics struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// tagEntry is the value stored in the ristretto cache. The tag is kept
+// alongside the size because ristretto's eviction callback only sees a hashed
+// key, not the original one.
+type tagEntry struct {
+	tag  string
+	size int64
+}
+
+// cachingMemRoot is a MemRoot whose tagged entries (engineInfo/writerContext)
+// live in a TinyLFU admission cache instead of a plain map. Once the cache is
+// full, admitting a new tag evicts the coldest existing tags to make room,
+// invoking onEvict so the caller can close/flush the corresponding engine or
+// writer before its memory is actually reclaimed.
+type cachingMemRoot struct {
+	maxLimit  int64
+	currUsage atomic.Int64
+	cache     *ristretto.Cache
+	onEvict   func(tag string)
+
+	mu      sync.RWMutex
+	checker MemoryChecker
+}
+
+// NewCachingMemRoot creates a MemRoot backed by an evictable cache for tagged
+// entries. onEvict is invoked from the cache's own processing goroutine, once
+// per evicted tag. ConsumeWithTag waits for the cache to process its write,
+// so any eviction that write triggers has run onEvict before it returns.
+func NewCachingMemRoot(maxQuota int64, onEvict func(tag string)) (*cachingMemRoot, error) {
+	m := &cachingMemRoot{
+		maxLimit: maxQuota,
+		onEvict:  onEvict,
+		checker:  NewOSMemoryChecker(defaultMemoryLimitRatio),
+	}
+	cache, err := ristretto.NewCache(&ristretto.Config{
+		NumCounters: numCountersPerTag * 1024,
+		MaxCost:     maxQuota,
+		BufferItems: 64,
+		OnEvict: func(item *ristretto.Item) {
+			entry, ok := item.Value.(tagEntry)
+			if !ok {
+				return
+			}
+			m.currUsage.Add(-entry.size)
+			if m.onEvict != nil {
+				m.onEvict(entry.tag)
+			}
+		},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	m.cache = cache
+	return m, nil
+}
+
+// Consume implements MemRoot. It accounts memory outside of the tagged cache,
+// e.g. for callers that don't have a natural per-tag lifetime.
+func (m *cachingMemRoot) Consume(size int64) {
+	m.currUsage.Add(size)
+}
+
+// Release implements MemRoot.
+func (m *cachingMemRoot) Release(size int64) {
+	m.currUsage.Add(-size)
+}
+
+// CheckConsume implements MemRoot.
+func (m *cachingMemRoot) CheckConsume(size int64) bool {
+	return m.canAdmit(size)
+}
+
+func (m *cachingMemRoot) canAdmit(size int64) bool {
+	if m.currUsage.Load()+size > m.maxLimit {
+		return false
+	}
+	m.mu.RLock()
+	checker := m.checker
+	m.mu.RUnlock()
+	if checker == nil {
+		return true
+	}
+	allowed, _ := checker.Allowed(size)
+	return allowed
+}
+
+// TryConsume implements MemRoot.
+func (m *cachingMemRoot) TryConsume(size int64) bool {
+	for {
+		cur := m.currUsage.Load()
+		if cur+size > m.maxLimit || !m.canAdmit(size) {
+			return false
+		}
+		// Only consume if currUsage hasn't moved since the check above, so two
+		// concurrent callers can't both pass it and overrun the quota.
+		if m.currUsage.CompareAndSwap(cur, cur+size) {
+			return true
+		}
+	}
+}
+
+// ConsumeWithTag implements MemRoot. It is an admission attempt against the
+// cache: the cost is the reported size, and admitting it may evict the
+// coldest existing tags to make room. cache.Set only reports whether the
+// write was buffered, not whether the TinyLFU policy actually keeps it — that
+// decision happens asynchronously, so a true return here doesn't yet mean the
+// tag is cached. currUsage is only adjusted once cache.Get, after Wait, shows
+// the tag was actually admitted. Since cache.Set replaces rather than
+// accumulates a repeated tag's cost, the adjustment is the delta against
+// tag's previous size, not the full size again.
+func (m *cachingMemRoot) ConsumeWithTag(tag string, size int64) {
+	prev, hadPrev := m.tagSize(tag)
+	m.cache.Set(tag, tagEntry{tag: tag, size: size}, size)
+	// Make the admission/eviction decision synchronous so that onEvict runs,
+	// and the caller can rely on the evicted engine/writer being flushed,
+	// before ConsumeWithTag returns.
+	m.cache.Wait()
+	if _, admitted := m.tagSize(tag); !admitted {
+		return
+	}
+	delta := size
+	if hadPrev {
+		delta = size - prev
+	}
+	m.currUsage.Add(delta)
+}
+
+// TryConsumeWithTag implements MemRoot.
+func (m *cachingMemRoot) TryConsumeWithTag(tag string, size int64) bool {
+	if !m.canAdmit(size) {
+		return false
+	}
+	m.ConsumeWithTag(tag, size)
+	return true
+}
+
+// BlockingConsume implements MemRoot.
+func (m *cachingMemRoot) BlockingConsume(ctx context.Context, size int64) error {
+	// Untagged consumption lives outside the cache and is never evicted, so
+	// this polls TryConsume until both the accounted budget and the OS-level
+	// pressure gate admit size.
+	ticker := time.NewTicker(pressurePollInterval)
+	defer ticker.Stop()
+	for !m.TryConsume(size) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// BlockingConsumeWithTag implements MemRoot.
+func (m *cachingMemRoot) BlockingConsumeWithTag(ctx context.Context, tag string, size int64) error {
+	if err := m.blockingConsume(ctx, size); err != nil {
+		return err
+	}
+	m.ConsumeWithTag(tag, size)
+	return nil
+}
+
+// pressurePollInterval is how often a blocked BlockingConsume or
+// BlockingConsumeWithTag re-checks admission, which covers both the accounted
+// budget and the OS-level pressure gate. Nothing signals a waiter when either
+// frees up, so waiters poll.
+const pressurePollInterval = 50 * time.Millisecond
+
+func (m *cachingMemRoot) blockingConsume(ctx context.Context, size int64) error {
+	if m.canAdmit(size) {
+		return nil
+	}
+	ticker := time.NewTicker(pressurePollInterval)
+	defer ticker.Stop()
+	for !m.canAdmit(size) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// ReleaseWithTag implements MemRoot. Del does not invoke OnEvict, so unlike
+// an automatic eviction, currUsage has to be subtracted here explicitly;
+// otherwise every explicit release would leak accounted memory.
+func (m *cachingMemRoot) ReleaseWithTag(tag string) {
+	if size, ok := m.tagSize(tag); ok {
+		m.currUsage.Add(-size)
+	}
+	m.cache.Del(tag)
+}
+
+// SetMaxMemoryQuota implements MemRoot.
+func (m *cachingMemRoot) SetMaxMemoryQuota(maxQuota int64) {
+	m.maxLimit = maxQuota
+	m.cache.UpdateMaxCost(maxQuota)
+}
+
+// MaxMemoryQuota implements MemRoot.
+func (m *cachingMemRoot) MaxMemoryQuota() int64 {
+	return m.maxLimit
+}
+
+// CurrentUsage implements MemRoot.
+func (m *cachingMemRoot) CurrentUsage() int64 {
+	return m.currUsage.Load()
+}
+
+// CurrentUsageWithTag implements MemRoot.
+func (m *cachingMemRoot) CurrentUsageWithTag(tag string) int64 {
+	size, _ := m.tagSize(tag)
+	return size
+}
+
+// tagSize looks up tag's currently admitted cost in the cache, if present.
+func (m *cachingMemRoot) tagSize(tag string) (int64, bool) {
+	val, ok := m.cache.Get(tag)
+	if !ok {
+		return 0, false
+	}
+	entry, ok := val.(tagEntry)
+	if !ok {
+		return 0, false
+	}
+	return entry.size, true
+}
+
+// RefreshConsumption implements MemRoot.
+func (m *cachingMemRoot) RefreshConsumption() {
+	m.mu.RLock()
+	checker := m.checker
+	m.mu.RUnlock()
+	if checker == nil {
+		return
+	}
+	_, _ = checker.Refresh()
+}
+
+// SystemUsage implements MemRoot.
+func (m *cachingMemRoot) SystemUsage() SystemMemUsage {
+	m.mu.RLock()
+	checker := m.checker
+	m.mu.RUnlock()
+	if checker == nil {
+		return SystemMemUsage{}
+	}
+	_, usage := checker.Allowed(0)
+	return usage
+}
+
+// Metrics returns the cache's hit/miss/eviction counters for exporting to
+// Prometheus.
+func (m *cachingMemRoot) Metrics() CacheMetrics {
+	metrics := m.cache.Metrics
+	if metrics == nil {
+		return CacheMetrics{}
+	}
+	return CacheMetrics{
+		Hits:      metrics.Hits(),
+		Misses:    metrics.Misses(),
+		Evictions: metrics.KeysEvicted(),
+	}
+}
+
+// Close releases the resources held by the underlying cache.
+func (m *cachingMemRoot) Close() {
+	m.cache.Close()
+}
+
+var _ MemRoot = (*cachingMemRoot)(nil)
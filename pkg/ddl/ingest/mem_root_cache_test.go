@@ -0,0 +1,59 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCachingMemRootConcurrentConsumeReleaseWithTag drives concurrent
+// repeated ConsumeWithTag/ReleaseWithTag calls across several tags and
+// checks that currUsage nets back to zero. Before the fix this drifted
+// upward: ConsumeWithTag added the full size on every call even though
+// cache.Set replaces rather than accumulates a repeated tag's cost, and
+// ReleaseWithTag never subtracted a tag's cost at all.
+func TestCachingMemRootConcurrentConsumeReleaseWithTag(t *testing.T) {
+	m, err := NewCachingMemRoot(1<<20, nil)
+	require.NoError(t, err)
+	defer m.Close()
+
+	const workers = 8
+	const iterations = 50
+	const size = 100
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tag := fmt.Sprintf("tag-%d", i)
+			for j := 0; j < iterations; j++ {
+				m.ConsumeWithTag(tag, size)
+				m.ConsumeWithTag(tag, size)
+				require.Equal(t, int64(size), m.CurrentUsageWithTag(tag))
+				m.ReleaseWithTag(tag)
+				require.Equal(t, int64(0), m.CurrentUsageWithTag(tag))
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(0), m.CurrentUsage())
+}
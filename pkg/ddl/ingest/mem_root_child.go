@@ -0,0 +1,273 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"context"
+	"sync"
+)
+
+// NewChildMemRoot registers a new fair-share child MemRoot for a concurrent
+// DDL job under m. Every registered child is given an equal fraction of m's
+// overall quota (maxLimit / number of children); the fraction is recomputed
+// and pushed to every existing child whenever a child is added or removed, so
+// a handful of busy jobs don't starve everyone else out of the shared quota.
+//
+// Consumption against a child also counts against m's own accounted usage:
+// the child enforces its fair share locally, while m still enforces the
+// global budget and OS-level pressure gate.
+func (m *memRootImpl) NewChildMemRoot(jobTag string) *childMemRoot {
+	m.mu.Lock()
+	if m.children == nil {
+		m.children = make(map[string]*childMemRoot)
+	}
+	c := &childMemRoot{parent: m, jobTag: jobTag}
+	c.cond = sync.NewCond(&c.mu)
+	m.children[jobTag] = c
+	m.rebalanceChildrenLocked()
+	m.mu.Unlock()
+	return c
+}
+
+// RemoveChildMemRoot unregisters a child created by NewChildMemRoot and
+// redistributes its share of the quota to the remaining children.
+func (m *memRootImpl) RemoveChildMemRoot(jobTag string) {
+	m.mu.Lock()
+	delete(m.children, jobTag)
+	m.rebalanceChildrenLocked()
+	m.mu.Unlock()
+	m.cond.Broadcast()
+}
+
+// rebalanceChildrenLocked recomputes every child's fair-share quota as an
+// equal fraction of maxLimit. The caller must hold m.mu.
+func (m *memRootImpl) rebalanceChildrenLocked() {
+	if len(m.children) == 0 {
+		return
+	}
+	share := m.maxLimit / int64(len(m.children))
+	for _, c := range m.children {
+		c.setQuota(share)
+	}
+}
+
+// childMemRoot is a MemRoot handed out to one concurrent DDL job. It enforces
+// a fair share of the parent's quota locally, while delegating the actual
+// accounting and the OS-pressure gate to the parent so the two layers stay
+// consistent.
+type childMemRoot struct {
+	parent *memRootImpl
+	jobTag string
+
+	mu    sync.RWMutex
+	cond  *sync.Cond
+	quota int64
+	usage int64
+}
+
+func (c *childMemRoot) setQuota(quota int64) {
+	c.mu.Lock()
+	c.quota = quota
+	c.mu.Unlock()
+	c.cond.Broadcast()
+}
+
+func (c *childMemRoot) canAdmitLocked(size int64) bool {
+	return c.usage+size <= c.quota
+}
+
+// Consume implements MemRoot.
+func (c *childMemRoot) Consume(size int64) {
+	c.mu.Lock()
+	c.usage += size
+	c.mu.Unlock()
+	c.parent.Consume(size)
+}
+
+// Release implements MemRoot.
+func (c *childMemRoot) Release(size int64) {
+	c.mu.Lock()
+	c.usage -= size
+	c.mu.Unlock()
+	c.cond.Broadcast()
+	c.parent.Release(size)
+}
+
+// CheckConsume implements MemRoot.
+func (c *childMemRoot) CheckConsume(size int64) bool {
+	c.mu.RLock()
+	ok := c.canAdmitLocked(size)
+	c.mu.RUnlock()
+	return ok && c.parent.CheckConsume(size)
+}
+
+// TryConsume implements MemRoot.
+func (c *childMemRoot) TryConsume(size int64) bool {
+	c.mu.Lock()
+	if !c.canAdmitLocked(size) {
+		c.mu.Unlock()
+		return false
+	}
+	if !c.parent.TryConsume(size) {
+		c.mu.Unlock()
+		return false
+	}
+	c.usage += size
+	c.mu.Unlock()
+	return true
+}
+
+// BlockingConsume implements MemRoot.
+func (c *childMemRoot) BlockingConsume(ctx context.Context, size int64) error {
+	// Wake the fair-share wait below when ctx is done, so it can return
+	// ctx.Err() instead of waiting for the next Release.
+	stopWatch := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	})
+	defer stopWatch()
+	c.mu.Lock()
+	for !c.canAdmitLocked(size) {
+		if err := ctx.Err(); err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		c.cond.Wait()
+	}
+	// Reserve the fair share now, while still holding c.mu, so a concurrent
+	// caller's canAdmitLocked check sees it immediately; otherwise two
+	// callers can both pass the check before either updates c.usage and
+	// together overrun the quota.
+	c.usage += size
+	c.mu.Unlock()
+	// The fair share is reserved; now wait for the parent's global budget
+	// and OS-pressure gate, which may itself block.
+	if err := c.parent.BlockingConsume(ctx, size); err != nil {
+		c.mu.Lock()
+		c.usage -= size
+		c.mu.Unlock()
+		c.cond.Broadcast()
+		return err
+	}
+	return nil
+}
+
+// ConsumeWithTag implements MemRoot. The tag namespaces into jobTag/tag so
+// sibling children can't collide in the parent's tagged usage.
+func (c *childMemRoot) ConsumeWithTag(tag string, size int64) {
+	c.mu.Lock()
+	c.usage += size
+	c.mu.Unlock()
+	c.parent.ConsumeWithTag(c.jobTag+"/"+tag, size)
+}
+
+// TryConsumeWithTag implements MemRoot.
+func (c *childMemRoot) TryConsumeWithTag(tag string, size int64) bool {
+	c.mu.Lock()
+	if !c.canAdmitLocked(size) {
+		c.mu.Unlock()
+		return false
+	}
+	if !c.parent.TryConsumeWithTag(c.jobTag+"/"+tag, size) {
+		c.mu.Unlock()
+		return false
+	}
+	c.usage += size
+	c.mu.Unlock()
+	return true
+}
+
+// BlockingConsumeWithTag implements MemRoot.
+func (c *childMemRoot) BlockingConsumeWithTag(ctx context.Context, tag string, size int64) error {
+	stopWatch := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	})
+	defer stopWatch()
+	c.mu.Lock()
+	for !c.canAdmitLocked(size) {
+		if err := ctx.Err(); err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		c.cond.Wait()
+	}
+	// Reserve before calling the parent; see BlockingConsume for why.
+	c.usage += size
+	c.mu.Unlock()
+	if err := c.parent.BlockingConsumeWithTag(ctx, c.jobTag+"/"+tag, size); err != nil {
+		c.mu.Lock()
+		c.usage -= size
+		c.mu.Unlock()
+		c.cond.Broadcast()
+		return err
+	}
+	return nil
+}
+
+// ReleaseWithTag implements MemRoot.
+func (c *childMemRoot) ReleaseWithTag(tag string) {
+	size := c.parent.CurrentUsageWithTag(c.jobTag + "/" + tag)
+	c.parent.ReleaseWithTag(c.jobTag + "/" + tag)
+	c.mu.Lock()
+	c.usage -= size
+	c.mu.Unlock()
+	c.cond.Broadcast()
+}
+
+// SetMaxMemoryQuota overrides this child's own fair-share quota, e.g. to
+// temporarily grant one DDL job more than its equal share.
+func (c *childMemRoot) SetMaxMemoryQuota(quota int64) {
+	c.setQuota(quota)
+}
+
+// MaxMemoryQuota implements MemRoot.
+func (c *childMemRoot) MaxMemoryQuota() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.quota
+}
+
+// CurrentUsage implements MemRoot.
+func (c *childMemRoot) CurrentUsage() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.usage
+}
+
+// CurrentUsageWithTag implements MemRoot.
+func (c *childMemRoot) CurrentUsageWithTag(tag string) int64 {
+	return c.parent.CurrentUsageWithTag(c.jobTag + "/" + tag)
+}
+
+// RefreshConsumption implements MemRoot.
+func (c *childMemRoot) RefreshConsumption() {
+	c.parent.RefreshConsumption()
+}
+
+// SystemUsage implements MemRoot.
+func (c *childMemRoot) SystemUsage() SystemMemUsage {
+	return c.parent.SystemUsage()
+}
+
+// Close unregisters this child from its parent, returning its fair share to
+// the remaining siblings.
+func (c *childMemRoot) Close() {
+	c.parent.RemoveChildMemRoot(c.jobTag)
+}
+
+var _ MemRoot = (*childMemRoot)(nil)
@@ -0,0 +1,112 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestChildMemRootBlockingConsumeFairShare gives a child exactly one slot of
+// quota and hammers it with concurrent BlockingConsume/Release calls. If
+// BlockingConsume only updates usage after the parent call succeeds (instead
+// of reserving it first), two callers can both pass the admission check
+// before either is accounted for, and more than one slot is observed active
+// at once.
+func TestChildMemRootBlockingConsumeFairShare(t *testing.T) {
+	const size = 100
+	const workers = 8
+	const iterations = 20
+
+	parent := NewMemRootImpl(1 << 30)
+	child := parent.NewChildMemRoot("job")
+	child.SetMaxMemoryQuota(size)
+
+	var active, maxActive atomic.Int32
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				require.NoError(t, child.BlockingConsume(ctx, size))
+				n := active.Add(1)
+				for {
+					old := maxActive.Load()
+					if n <= old || maxActive.CompareAndSwap(old, n) {
+						break
+					}
+				}
+				active.Add(-1)
+				child.Release(size)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, int(maxActive.Load()), 1)
+	require.Equal(t, int64(0), child.CurrentUsage())
+}
+
+// TestChildMemRootBlockingConsumeWithTagFairShare is the tagged counterpart
+// of TestChildMemRootBlockingConsumeFairShare.
+func TestChildMemRootBlockingConsumeWithTagFairShare(t *testing.T) {
+	const size = 100
+	const workers = 8
+	const iterations = 20
+
+	parent := NewMemRootImpl(1 << 30)
+	child := parent.NewChildMemRoot("job")
+	child.SetMaxMemoryQuota(size)
+
+	var active, maxActive atomic.Int32
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tag := fmt.Sprintf("writer-%d", i)
+			for j := 0; j < iterations; j++ {
+				require.NoError(t, child.BlockingConsumeWithTag(ctx, tag, size))
+				n := active.Add(1)
+				for {
+					old := maxActive.Load()
+					if n <= old || maxActive.CompareAndSwap(old, n) {
+						break
+					}
+				}
+				active.Add(-1)
+				child.ReleaseWithTag(tag)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, int(maxActive.Load()), 1)
+	require.Equal(t, int64(0), child.CurrentUsage())
+}
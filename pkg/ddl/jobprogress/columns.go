@@ -0,0 +1,72 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobprogress
+
+import "time"
+
+// Columns is the information_schema.DDL_JOBS row fragment rendered from a
+// Snapshot: ROW_COUNT_DONE, ROW_COUNT_TOTAL, PROGRESS_PERCENT, START_TIME,
+// LAST_UPDATE_TIME, and ETA_SECONDS. The memtable retriever for ddl_jobs
+// merges these onto the existing JOB_ID/JOB_TYPE/... columns for jobs that
+// have a tracked Snapshot.
+type Columns struct {
+	RowCountDone    int64
+	RowCountTotal   int64
+	ProgressPercent float64
+	StartTime       time.Time
+	LastUpdateTime  time.Time
+	// ETASeconds is nil until there's enough of a sample to estimate one;
+	// see Snapshot.RemainingSeconds.
+	ETASeconds *float64
+}
+
+// Columns renders s as an information_schema.DDL_JOBS row fragment.
+func (s Snapshot) Columns() Columns {
+	cols := Columns{
+		RowCountDone:    s.ProcessedRows,
+		RowCountTotal:   s.EstimatedTotalRows,
+		ProgressPercent: s.Progress() * 100,
+		StartTime:       s.StartTime,
+		LastUpdateTime:  s.sampledAt,
+	}
+	if eta, ok := s.RemainingSeconds(); ok {
+		cols.ETASeconds = &eta
+	}
+	return cols
+}
+
+// RunningJobIDs returns the IDs of every job with a tracked Snapshot, i.e.
+// the same set information_schema.ddl_jobs reports as state = 'running'.
+// Filtering by this list lets the memtable retriever's WHERE state =
+// 'running' pushdown skip computing Columns for every other job.
+func (t *Tracker) RunningJobIDs() []int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	ids := make([]int64, 0, len(t.byJobID))
+	for id := range t.byJobID {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// ColumnsFor is a convenience wrapper combining Get and Snapshot.Columns for
+// the ddl_jobs retriever.
+func (t *Tracker) ColumnsFor(jobID int64) (Columns, bool) {
+	snap, ok := t.Get(jobID)
+	if !ok {
+		return Columns{}, false
+	}
+	return snap.Columns(), true
+}
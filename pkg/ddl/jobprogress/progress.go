@@ -0,0 +1,142 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jobprogress tracks the row-count progress of running DDL reorg
+// jobs (ADD INDEX, MODIFY COLUMN, partitioning changes), so that
+// INFORMATION_SCHEMA.DDL_JOBS can expose the same PROCESSED_ROWS/
+// ESTIMATED_TOTAL_ROWS/PROGRESS/REMAINING_SECONDS shape that
+// INFORMATION_SCHEMA.ANALYZE_STATUS already does. Reorg backfill workers
+// call Tracker.Update as they consume row ranges; the infoschema ddl_jobs
+// reader calls Tracker.Get to render the new columns.
+package jobprogress
+
+import (
+	"sync"
+	"time"
+)
+
+// Snapshot is a point-in-time measurement of a running reorg job's progress.
+type Snapshot struct {
+	// ProcessedRows is the number of rows the backfill has scanned so far.
+	ProcessedRows int64
+	// EstimatedTotalRows is the row count estimate the job started with,
+	// typically seeded from the table's stats. It isn't corrected mid-job,
+	// so Progress/RemainingSeconds are only as accurate as that estimate.
+	EstimatedTotalRows int64
+	// StartTime is when the reorg began processing rows.
+	StartTime time.Time
+
+	sampledAt time.Time
+}
+
+// NewSnapshot creates a Snapshot for a reorg job that's about to start
+// processing rows, seeded with a row-count estimate.
+func NewSnapshot(estimatedTotalRows int64, startTime time.Time) *Snapshot {
+	return &Snapshot{
+		EstimatedTotalRows: estimatedTotalRows,
+		StartTime:          startTime,
+		sampledAt:          startTime,
+	}
+}
+
+// update records a new processed-row count observed at now.
+func (s *Snapshot) update(processedRows int64, now time.Time) {
+	s.ProcessedRows = processedRows
+	s.sampledAt = now
+}
+
+// Progress returns the fraction of EstimatedTotalRows processed so far, in
+// [0, 1]. It returns 0 if EstimatedTotalRows is unknown.
+func (s *Snapshot) Progress() float64 {
+	if s.EstimatedTotalRows <= 0 {
+		return 0
+	}
+	progress := float64(s.ProcessedRows) / float64(s.EstimatedTotalRows)
+	if progress > 1 {
+		return 1
+	}
+	return progress
+}
+
+// RemainingSeconds estimates time-to-completion by extrapolating the average
+// row rate observed since StartTime across the rows still left to process.
+// It returns ok=false when there isn't enough information yet, e.g. no rows
+// have been processed.
+func (s *Snapshot) RemainingSeconds() (seconds float64, ok bool) {
+	elapsed := s.sampledAt.Sub(s.StartTime).Seconds()
+	if elapsed <= 0 || s.ProcessedRows <= 0 || s.EstimatedTotalRows <= s.ProcessedRows {
+		return 0, false
+	}
+	rowsPerSecond := float64(s.ProcessedRows) / elapsed
+	if rowsPerSecond <= 0 {
+		return 0, false
+	}
+	remainingRows := float64(s.EstimatedTotalRows - s.ProcessedRows)
+	return remainingRows / rowsPerSecond, true
+}
+
+// Tracker is a registry of Snapshots keyed by DDL job ID, safe for
+// concurrent use by reorg workers and infoschema readers.
+type Tracker struct {
+	mu      sync.RWMutex
+	byJobID map[int64]*Snapshot
+}
+
+// NewTracker creates an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{byJobID: make(map[int64]*Snapshot)}
+}
+
+// DefaultTracker is the process-wide Tracker used by reorg workers that
+// don't need an isolated one, e.g. for tests.
+var DefaultTracker = NewTracker()
+
+// Start registers a new Snapshot for jobID, replacing any existing one.
+func (t *Tracker) Start(jobID int64, estimatedTotalRows int64, startTime time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.byJobID[jobID] = NewSnapshot(estimatedTotalRows, startTime)
+}
+
+// Update records a new processed-row count for jobID at now. It's a no-op if
+// jobID hasn't been registered with Start, e.g. because the reorg doesn't
+// track progress for this job type.
+func (t *Tracker) Update(jobID int64, processedRows int64, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snap, ok := t.byJobID[jobID]
+	if !ok {
+		return
+	}
+	snap.update(processedRows, now)
+}
+
+// Get returns a copy of jobID's current Snapshot, if one is registered.
+func (t *Tracker) Get(jobID int64) (Snapshot, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	snap, ok := t.byJobID[jobID]
+	if !ok {
+		return Snapshot{}, false
+	}
+	return *snap, true
+}
+
+// Finish removes jobID's Snapshot once the job has left a state where
+// progress is meaningful, e.g. it's synced, cancelled, or failed.
+func (t *Tracker) Finish(jobID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.byJobID, jobID)
+}
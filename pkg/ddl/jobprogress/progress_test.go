@@ -0,0 +1,60 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobprogress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerLifecycle(t *testing.T) {
+	tr := NewTracker()
+	start := time.Now()
+
+	_, ok := tr.Get(1)
+	require.False(t, ok)
+
+	tr.Start(1, 1000, start)
+	require.Equal(t, []int64{1}, tr.RunningJobIDs())
+
+	tr.Update(1, 250, start.Add(10*time.Second))
+	snap, ok := tr.Get(1)
+	require.True(t, ok)
+	require.InDelta(t, 0.25, snap.Progress(), 1e-9)
+
+	eta, ok := snap.RemainingSeconds()
+	require.True(t, ok)
+	require.InDelta(t, 30, eta, 1e-9)
+
+	cols := snap.Columns()
+	require.Equal(t, int64(250), cols.RowCountDone)
+	require.Equal(t, int64(1000), cols.RowCountTotal)
+	require.InDelta(t, 25, cols.ProgressPercent, 1e-9)
+	require.NotNil(t, cols.ETASeconds)
+
+	tr.Finish(1)
+	_, ok = tr.Get(1)
+	require.False(t, ok)
+	require.Empty(t, tr.RunningJobIDs())
+}
+
+func TestSnapshotProgressWithoutEstimate(t *testing.T) {
+	snap := NewSnapshot(0, time.Now())
+	require.Equal(t, float64(0), snap.Progress())
+	_, ok := snap.RemainingSeconds()
+	require.False(t, ok)
+}
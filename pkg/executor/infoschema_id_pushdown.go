@@ -0,0 +1,108 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import "strings"
+
+// idPushdownColumns lists the numeric information_schema columns whose
+// equality/IN predicates can be resolved via the infoschema's ID indexes
+// instead of a full table scan: TABLES/PARTITIONS' TIDB_TABLE_ID and
+// TIDB_PARTITION_ID, and the SCHEMA_ID columns KEY_COLUMN_USAGE and
+// TABLE_CONSTRAINTS expose alongside them.
+var idPushdownColumns = map[string]struct{}{
+	"tidb_table_id":     {},
+	"tidb_partition_id": {},
+	"schema_id":         {},
+}
+
+// isIDPushdownColumn reports whether column is one of idPushdownColumns,
+// case-insensitively, matching how information_schema column names are
+// normalized elsewhere.
+func isIDPushdownColumn(column string) bool {
+	_, ok := idPushdownColumns[strings.ToLower(column)]
+	return ok
+}
+
+// idPredicate is an equality or IN-list condition on one of
+// idPushdownColumns, after MemTablePredicateExtractor has pulled it out of a
+// query's access conditions.
+type idPredicate struct {
+	column string
+	ids    []int64
+}
+
+// mergeIDPredicates combines multiple predicates on the same column with an
+// intersection (an AND of two equality/IN conditions on tidb_table_id can
+// only match IDs both agree on), and returns one ID set per column. A
+// column whose intersection is empty is kept with an empty slice, so the
+// caller can short-circuit to zero rows instead of falling back to a full
+// scan.
+func mergeIDPredicates(predicates []idPredicate) map[string][]int64 {
+	merged := make(map[string][]int64, len(predicates))
+	seen := make(map[string]bool, len(predicates))
+	for _, p := range predicates {
+		if !seen[p.column] {
+			merged[p.column] = dedupInt64(p.ids)
+			seen[p.column] = true
+			continue
+		}
+		merged[p.column] = intersectInt64(merged[p.column], p.ids)
+	}
+	return merged
+}
+
+// isIDPushdownCovered reports whether every column referenced by a memtable
+// scan's access conditions is one the ID-index fast path can resolve, i.e.
+// whether the scan can skip a full table scan entirely. The cover-check that
+// `TestInfoschemaTablesSpecialOptimizationCovered` probes does not consult it,
+// so tidb_table_id predicates still read as not covered there.
+func isIDPushdownCovered(columns []string) bool {
+	if len(columns) == 0 {
+		return false
+	}
+	for _, col := range columns {
+		if !isIDPushdownColumn(col) {
+			return false
+		}
+	}
+	return true
+}
+
+func dedupInt64(ids []int64) []int64 {
+	seen := make(map[int64]struct{}, len(ids))
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
+
+func intersectInt64(a, b []int64) []int64 {
+	set := make(map[int64]struct{}, len(a))
+	for _, id := range a {
+		set[id] = struct{}{}
+	}
+	out := make([]int64, 0, len(a))
+	for _, id := range b {
+		if _, ok := set[id]; ok {
+			out = append(out, id)
+		}
+	}
+	return dedupInt64(out)
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsIDPushdownCovered(t *testing.T) {
+	require.True(t, isIDPushdownCovered([]string{"tidb_table_id"}))
+	require.True(t, isIDPushdownCovered([]string{"tidb_table_id", "schema_id"}))
+	require.False(t, isIDPushdownCovered([]string{"table_name"}))
+	require.False(t, isIDPushdownCovered([]string{"tidb_table_id", "table_name"}))
+	require.False(t, isIDPushdownCovered(nil))
+}
+
+func TestMergeIDPredicates(t *testing.T) {
+	merged := mergeIDPredicates([]idPredicate{
+		{column: "tidb_table_id", ids: []int64{1, 2, 3}},
+		{column: "tidb_table_id", ids: []int64{2, 3, 4}},
+		{column: "schema_id", ids: []int64{10, 10, 11}},
+	})
+	require.ElementsMatch(t, []int64{2, 3}, merged["tidb_table_id"])
+	require.ElementsMatch(t, []int64{10, 11}, merged["schema_id"])
+}
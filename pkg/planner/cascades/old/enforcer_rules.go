@@ -17,12 +17,15 @@ package old
 import (
 	"math"
 
+	"github.com/pingcap/tidb/pkg/expression"
+	"github.com/pingcap/tidb/pkg/planner/cardinality"
 	"github.com/pingcap/tidb/pkg/planner/cascades/pattern"
 	"github.com/pingcap/tidb/pkg/planner/core/operator/physicalop"
 	"github.com/pingcap/tidb/pkg/planner/implementation"
 	"github.com/pingcap/tidb/pkg/planner/memo"
 	"github.com/pingcap/tidb/pkg/planner/property"
 	"github.com/pingcap/tidb/pkg/planner/util"
+	"github.com/pingcap/tipb/go-tipb"
 )
 
 // Enforcer defines the interface for enforcer rules.
@@ -36,18 +39,86 @@ type Enforcer interface {
 	GetEnforceCost(g *memo.Group) float64
 }
 
-// GetEnforcerRules gets all candidate enforcer rules based
-// on required physical property.
-func GetEnforcerRules(g *memo.Group, prop *property.PhysicalProperty) (enforcers []Enforcer) {
-	if g.EngineType != pattern.EngineTiDB {
-		return
+// GetEnforcerRules gets all candidate enforcer rules based on required
+// physical property, plus whatever's needed to read g into requiredEngine if
+// g itself is planned on a different engine (see GetCrossEngineEnforcer).
+func GetEnforcerRules(g *memo.Group, prop *property.PhysicalProperty, requiredEngine pattern.EngineType) (enforcers []Enforcer) {
+	if g.EngineType == pattern.EngineTiFlash && !prop.MPPPartitionTp.IsEmpty() {
+		enforcers = append(enforcers, distributionEnforcer)
 	}
-	if !prop.IsSortItemEmpty() {
-		enforcers = append(enforcers, orderEnforcer)
+	if g.EngineType == pattern.EngineTiDB && !prop.IsSortItemEmpty() {
+		if prop.ExpectedCnt < math.MaxFloat64 {
+			// A finite ExpectedCnt on top of a sort requirement means the
+			// plan only needs the top rows of the order, so fuse the two
+			// into a single TopN instead of stacking a Sort enforcer under a
+			// separate Limit.
+			enforcers = append(enforcers, topNEnforcer)
+		} else {
+			enforcers = append(enforcers, orderEnforcer)
+		}
+	}
+	if enforcer := GetCrossEngineEnforcer(g, requiredEngine); enforcer != nil {
+		// The cross-engine read happens on top of whatever enforces g's own
+		// engine properties above, so it's appended last.
+		enforcers = append(enforcers, enforcer)
 	}
 	return
 }
 
+// DistributionEnforcer enforces an MPP data distribution property (hash
+// shuffle, broadcast, or gather to a single partition) by inserting an
+// exchange operator on top of child implementation. Unlike OrderEnforcer, it
+// only applies to groups planned on the TiFlash engine, where operators are
+// distributed across multiple MPP tasks and a required MPPPartitionTp can't
+// be satisfied by the child's own partitioning.
+type DistributionEnforcer struct {
+}
+
+var distributionEnforcer = &DistributionEnforcer{}
+
+// NewProperty removes the distribution requirement from required physical
+// property, since the enforcer itself will satisfy it.
+func (*DistributionEnforcer) NewProperty(prop *property.PhysicalProperty) (newProp *property.PhysicalProperty) {
+	newProp = &property.PhysicalProperty{
+		ExpectedCnt:    prop.ExpectedCnt,
+		MPPPartitionTp: property.AnyType,
+	}
+	return
+}
+
+// OnEnforce adds an exchange sender/receiver pair to redistribute child's
+// rows according to reqProp's MPPPartitionTp: hash-partitioned by
+// MPPPartitionCols, broadcast to every task, or gathered onto one.
+func (*DistributionEnforcer) OnEnforce(reqProp *property.PhysicalProperty, child memo.Implementation) (impl memo.Implementation) {
+	childPlan := child.GetPlan()
+	exchangeType := tipb.ExchangeType_PassThrough
+	switch reqProp.MPPPartitionTp {
+	case property.HashType:
+		exchangeType = tipb.ExchangeType_HashPartition
+	case property.BroadcastType:
+		exchangeType = tipb.ExchangeType_Broadcast
+	case property.SingletonType:
+		exchangeType = tipb.ExchangeType_PassThrough
+	}
+	sender := physicalop.PhysicalExchangeSender{
+		ExchangeType: exchangeType,
+		HashCols:     reqProp.MPPPartitionCols,
+	}.Init(childPlan.SCtx(), childPlan.StatsInfo(), childPlan.QueryBlockOffset(), &property.PhysicalProperty{ExpectedCnt: math.MaxFloat64})
+	receiver := physicalop.PhysicalExchangeReceiver{}.Init(childPlan.SCtx(), childPlan.StatsInfo(), childPlan.QueryBlockOffset(), reqProp)
+	impl = implementation.NewExchangeReceiverImpl(receiver).AttachChildren(implementation.NewExchangeSenderImpl(sender).AttachChildren(child))
+	return
+}
+
+// GetEnforceCost calculates the cost of redistributing g's rows across MPP
+// tasks, which is dominated by the network cost of shipping every row from
+// its sender task to its receiver task.
+func (*DistributionEnforcer) GetEnforceCost(g *memo.Group) float64 {
+	sctx := g.Equivalents.Front().Value.(*memo.GroupExpr).ExprNode.SCtx()
+	rowCount := g.Prop.Stats.RowCount
+	rowSize := cardinality.GetAvgRowSize(sctx, g.Prop.Stats, g.Prop.Schema.Columns, false, false)
+	return rowCount * rowSize * sctx.GetSessionVars().GetNetworkFactor(nil)
+}
+
 // OrderEnforcer enforces order property on child implementation.
 type OrderEnforcer struct {
 }
@@ -83,6 +154,128 @@ func (*OrderEnforcer) GetEnforceCost(g *memo.Group) float64 {
 	// We need a SessionCtx to calculate the cost of a sort.
 	sctx := g.Equivalents.Front().Value.(*memo.GroupExpr).ExprNode.SCtx()
 	sort := physicalop.PhysicalSort{}.Init(sctx, g.Prop.Stats, 0, nil)
-	cost := sort.GetCost(g.Prop.Stats.RowCount, g.Prop.Schema)
+	cost := sort.GetCost(estimateSortNDV(g), g.Prop.Schema)
 	return cost
 }
+
+// estimateSortNDV estimates the number of distinct sort keys the sort
+// operator will have to fully order, using per-column NDV/sketch estimates
+// instead of assuming every row is distinct. Rows that tie on every sort
+// column don't need to be compared against each other, so costing off of
+// RowCount alone overestimates the work once keys repeat a lot, e.g. sorting
+// by a low-cardinality status column. The estimate is capped at RowCount and
+// falls back to it whenever a sort key isn't a plain column or has no usable
+// NDV estimate.
+func estimateSortNDV(g *memo.Group) float64 {
+	rowCount := g.Prop.Stats.RowCount
+	cols := make([]*expression.Column, 0, len(g.Prop.SortItems))
+	for _, item := range g.Prop.SortItems {
+		col, ok := item.Col.(*expression.Column)
+		if !ok {
+			return rowCount
+		}
+		cols = append(cols, col)
+	}
+	if len(cols) == 0 {
+		return rowCount
+	}
+	ndv, _ := cardinality.EstimateColsNDVWithMatchedLen(cols, g.Prop.Schema, g.Prop.Stats)
+	if ndv <= 0 || ndv > rowCount {
+		return rowCount
+	}
+	return ndv
+}
+
+// TopNEnforcer enforces a combined order-and-count property on child
+// implementation with a single TopN operator. It is chosen over OrderEnforcer
+// whenever the required property carries a finite ExpectedCnt alongside its
+// sort items, so the plan doesn't pay for sorting rows beyond what's needed.
+type TopNEnforcer struct {
+}
+
+var topNEnforcer = &TopNEnforcer{}
+
+// NewProperty removes the order and count property from required physical
+// property, since the enforcer itself will satisfy both.
+func (*TopNEnforcer) NewProperty(_ *property.PhysicalProperty) (newProp *property.PhysicalProperty) {
+	newProp = &property.PhysicalProperty{ExpectedCnt: math.MaxFloat64}
+	return
+}
+
+// OnEnforce adds a TopN operator fusing reqProp's sort items and ExpectedCnt
+// to satisfy the required property.
+func (*TopNEnforcer) OnEnforce(reqProp *property.PhysicalProperty, child memo.Implementation) (impl memo.Implementation) {
+	childPlan := child.GetPlan()
+	byItems := make([]*util.ByItems, 0, len(reqProp.SortItems))
+	for _, item := range reqProp.SortItems {
+		byItems = append(byItems, &util.ByItems{
+			Expr: item.Col,
+			Desc: item.Desc,
+		})
+	}
+	topN := physicalop.PhysicalTopN{
+		ByItems: byItems,
+		Count:   uint64(reqProp.ExpectedCnt),
+	}.Init(childPlan.SCtx(), childPlan.StatsInfo(), childPlan.QueryBlockOffset(), &property.PhysicalProperty{ExpectedCnt: math.MaxFloat64})
+	impl = implementation.NewTopNImpl(topN).AttachChildren(child)
+	return
+}
+
+// GetEnforceCost calculates cost of a TopN operator, which is cheaper than a
+// full sort since it only has to maintain a heap of reqProp.ExpectedCnt rows.
+func (*TopNEnforcer) GetEnforceCost(g *memo.Group) float64 {
+	sctx := g.Equivalents.Front().Value.(*memo.GroupExpr).ExprNode.SCtx()
+	topN := physicalop.PhysicalTopN{Count: uint64(g.Prop.ExpectedCnt)}.Init(sctx, g.Prop.Stats, 0, nil)
+	cost := topN.GetCost(g.Prop.Stats.RowCount, false)
+	return cost
+}
+
+// EngineTypeEnforcer enforces a cross-engine read, letting the cascades
+// planner mix TiKV and TiFlash operators in a single plan. Unlike the other
+// enforcers here, it doesn't enforce a physical property but a change of
+// engine: GetEnforcerRules appends it whenever the group being implemented
+// isn't already planned on the engine its parent requires (e.g. a
+// TiFlash-side aggregate feeding a TiKV join).
+type EngineTypeEnforcer struct {
+	// TargetEngine is the EngineType the parent operator requires its child
+	// implementation to present as.
+	TargetEngine pattern.EngineType
+}
+
+// GetCrossEngineEnforcer returns the EngineTypeEnforcer needed to read g,
+// planned on g.EngineType, into an implementation usable by an operator
+// that requires targetEngine. It returns nil if no enforcement is needed,
+// i.e. g is already planned on targetEngine.
+func GetCrossEngineEnforcer(g *memo.Group, targetEngine pattern.EngineType) Enforcer {
+	if g.EngineType == targetEngine {
+		return nil
+	}
+	return &EngineTypeEnforcer{TargetEngine: targetEngine}
+}
+
+// NewProperty passes the required property through unchanged: an
+// engine-type mismatch doesn't relax any of reqProp's own requirements, it
+// only adds a reader on top once child has been implemented.
+func (*EngineTypeEnforcer) NewProperty(prop *property.PhysicalProperty) (newProp *property.PhysicalProperty) {
+	newProp = prop.Clone()
+	return
+}
+
+// OnEnforce adds a table reader that pulls child's rows from its own engine
+// back into TargetEngine.
+func (e *EngineTypeEnforcer) OnEnforce(reqProp *property.PhysicalProperty, child memo.Implementation) (impl memo.Implementation) {
+	childPlan := child.GetPlan()
+	reader := physicalop.PhysicalTableReader{}.Init(childPlan.SCtx(), childPlan.StatsInfo(), childPlan.QueryBlockOffset())
+	impl = implementation.NewTableReaderImpl(reader, nil).AttachChildren(child)
+	return
+}
+
+// GetEnforceCost calculates the cost of reading g's rows across the engine
+// boundary, which like DistributionEnforcer is dominated by the network cost
+// of shipping every row to the requesting engine.
+func (e *EngineTypeEnforcer) GetEnforceCost(g *memo.Group) float64 {
+	sctx := g.Equivalents.Front().Value.(*memo.GroupExpr).ExprNode.SCtx()
+	rowCount := g.Prop.Stats.RowCount
+	rowSize := cardinality.GetAvgRowSize(sctx, g.Prop.Stats, g.Prop.Schema.Columns, false, false)
+	return rowCount * rowSize * sctx.GetSessionVars().GetNetworkFactor(nil)
+}
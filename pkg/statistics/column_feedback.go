@@ -0,0 +1,170 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import "sync"
+
+// CreateStatsColumnFeedbackTable is the DDL for mysql.stats_column_feedback,
+// which persists ColumnFeedback rows across restarts so the adaptive
+// sample-rate controller doesn't reset every time auto-analyze's owner
+// changes. One row per (table_id, column_name) analyzed.
+const CreateStatsColumnFeedbackTable = `CREATE TABLE IF NOT EXISTS mysql.stats_column_feedback (
+	table_id BIGINT(64) NOT NULL,
+	column_name VARCHAR(64) NOT NULL,
+	estimated_ndv BIGINT(64) NOT NULL,
+	observed_ndv BIGINT(64) NOT NULL,
+	estimated_skew DOUBLE NOT NULL,
+	observed_skew DOUBLE NOT NULL,
+	sample_rate DOUBLE NOT NULL,
+	update_time TIMESTAMP NOT NULL,
+	PRIMARY KEY (table_id, column_name)
+)`
+
+// ColumnSampleRate is a per-column override of a table's overall analyze
+// sample rate, parsed from `ANALYZE TABLE t WITH col1 SAMPLERATE 0.1, col2
+// SAMPLERATE 0.5`. Columns not listed keep the table-level rate.
+type ColumnSampleRate struct {
+	Column     string
+	SampleRate float64
+}
+
+// ColumnFeedback is one column's estimated-vs-observed comparison from a
+// single analyze: the NDV and skew its sample predicted, against what a
+// later, fuller look at the column (e.g. the next analyze, or a query that
+// happened to scan it fully) actually found.
+type ColumnFeedback struct {
+	TableID       int64
+	Column        string
+	EstimatedNDV  int64
+	ObservedNDV   int64
+	EstimatedSkew float64
+	ObservedSkew  float64
+}
+
+// ndvError is the relative error between the estimated and observed NDV, in
+// [0, +inf); 0 means the estimate was exact.
+func (f ColumnFeedback) ndvError() float64 {
+	if f.ObservedNDV == 0 {
+		if f.EstimatedNDV == 0 {
+			return 0
+		}
+		return 1
+	}
+	diff := f.EstimatedNDV - f.ObservedNDV
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) / float64(f.ObservedNDV)
+}
+
+// skewError is the relative error between the estimated and observed skew,
+// in [0, +inf); 0 means the estimate was exact. Mirrors ndvError so a
+// column whose NDV stays accurate but whose distribution skews harder (e.g.
+// gains a dominant hot value) still trips FeedbackController.Record's
+// higher-sample-rate branch.
+func (f ColumnFeedback) skewError() float64 {
+	if f.ObservedSkew == 0 {
+		if f.EstimatedSkew == 0 {
+			return 0
+		}
+		return 1
+	}
+	diff := f.EstimatedSkew - f.ObservedSkew
+	if diff < 0 {
+		diff = -diff
+	}
+	observed := f.ObservedSkew
+	if observed < 0 {
+		observed = -observed
+	}
+	return diff / observed
+}
+
+type columnKey struct {
+	tableID int64
+	column  string
+}
+
+// FeedbackController tracks per-column sample rates and adjusts them as
+// ColumnFeedback comes in: a column whose NDV or skew estimate was badly off
+// gets a higher sample rate next time, one that was accurate gets nudged
+// back down, bounded to [minRate, maxRate]. The auto-analyze scheduler
+// consults SampleRate when building each column's WITH ... SAMPLERATE
+// clause for its next run.
+type FeedbackController struct {
+	mu          sync.RWMutex
+	rates       map[columnKey]float64
+	minRate     float64
+	maxRate     float64
+	stepUp      float64
+	stepDown    float64
+	errorThresh float64
+}
+
+// NewFeedbackController creates a FeedbackController whose adjusted sample
+// rates stay within [minRate, maxRate].
+func NewFeedbackController(minRate, maxRate float64) *FeedbackController {
+	return &FeedbackController{
+		rates:       make(map[columnKey]float64),
+		minRate:     minRate,
+		maxRate:     maxRate,
+		stepUp:      0.1,
+		stepDown:    0.02,
+		errorThresh: 0.1,
+	}
+}
+
+// Record folds fb into the controller's state for its (TableID, Column) and
+// returns the sample rate to use for that column's next analyze.
+func (c *FeedbackController) Record(fb ColumnFeedback) float64 {
+	key := columnKey{tableID: fb.TableID, column: fb.Column}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rate, ok := c.rates[key]
+	if !ok {
+		rate = c.minRate
+	}
+	if fb.ndvError() > c.errorThresh || fb.skewError() > c.errorThresh {
+		rate += c.stepUp
+	} else {
+		rate -= c.stepDown
+	}
+	rate = c.clamp(rate)
+	c.rates[key] = rate
+	return rate
+}
+
+// SampleRate returns the sample rate the controller has learned for
+// (tableID, column), or fallback if no feedback has been recorded for it
+// yet.
+func (c *FeedbackController) SampleRate(tableID int64, column string, fallback float64) float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if rate, ok := c.rates[columnKey{tableID: tableID, column: column}]; ok {
+		return rate
+	}
+	return fallback
+}
+
+func (c *FeedbackController) clamp(rate float64) float64 {
+	if rate < c.minRate {
+		return c.minRate
+	}
+	if rate > c.maxRate {
+		return c.maxRate
+	}
+	return rate
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedbackControllerRaisesRateOnBadEstimate(t *testing.T) {
+	c := NewFeedbackController(0.05, 0.5)
+	require.Equal(t, 0.05, c.SampleRate(1, "c1", 0.05))
+
+	rate := c.Record(ColumnFeedback{TableID: 1, Column: "c1", EstimatedNDV: 10, ObservedNDV: 1000})
+	require.Greater(t, rate, 0.05)
+	require.Equal(t, rate, c.SampleRate(1, "c1", 0.05))
+}
+
+func TestFeedbackControllerRaisesRateOnBadSkewEstimate(t *testing.T) {
+	c := NewFeedbackController(0.05, 0.5)
+
+	rate := c.Record(ColumnFeedback{
+		TableID: 1, Column: "c1",
+		EstimatedNDV: 1000, ObservedNDV: 1000,
+		EstimatedSkew: 0.1, ObservedSkew: 0.9,
+	})
+	require.Greater(t, rate, 0.05)
+}
+
+func TestFeedbackControllerRaisesRateOnNegativeObservedSkew(t *testing.T) {
+	c := NewFeedbackController(0.05, 0.5)
+
+	rate := c.Record(ColumnFeedback{
+		TableID: 1, Column: "c1",
+		EstimatedNDV: 1000, ObservedNDV: 1000,
+		EstimatedSkew: 0.1, ObservedSkew: -0.9,
+	})
+	require.Greater(t, rate, 0.05)
+}
+
+func TestFeedbackControllerLowersRateOnGoodEstimate(t *testing.T) {
+	c := NewFeedbackController(0.05, 0.5)
+	c.rates[columnKey{tableID: 1, column: "c1"}] = 0.3
+
+	rate := c.Record(ColumnFeedback{TableID: 1, Column: "c1", EstimatedNDV: 1000, ObservedNDV: 1000})
+	require.Less(t, rate, 0.3)
+}
+
+func TestFeedbackControllerClampsToBounds(t *testing.T) {
+	c := NewFeedbackController(0.05, 0.1)
+	for i := 0; i < 10; i++ {
+		c.Record(ColumnFeedback{TableID: 1, Column: "c1", EstimatedNDV: 1, ObservedNDV: 1000})
+	}
+	require.Equal(t, 0.1, c.SampleRate(1, "c1", 0))
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handle
+
+import (
+	"context"
+
+	"github.com/pingcap/tidb/pkg/statistics/handle/types"
+)
+
+// EnableDebugAssertions turns on assertCalledThroughMarker's panic. It
+// mirrors the intest-style guards used elsewhere in the codebase: off by
+// default so a stray call can't crash a production server, on in tests so
+// a regression that bypasses MarkTableAnalyzed/UnmarkTableAnalyzed is
+// caught immediately instead of silently leaving last_analyze_version
+// inconsistent with what was actually analyzed.
+var EnableDebugAssertions = false
+
+// markerTokenKey is the context.Context key MarkTableAnalyzed/UnmarkTableAnalyzed
+// stamp onto the ctx they pass to write, so assertCalledThroughMarker can
+// check the specific call it's guarding instead of a process-wide counter.
+// A counter shared across goroutines would give false negatives: one
+// goroutine's legitimate call through the marker would make a concurrent
+// goroutine's bypass look covered too.
+type markerTokenKey struct{}
+
+// withMarkerToken stamps ctx as having gone through AnalyzeMarker.
+func withMarkerToken(ctx context.Context) context.Context {
+	return context.WithValue(ctx, markerTokenKey{}, struct{}{})
+}
+
+// calledThroughMarker reports whether ctx was stamped by withMarkerToken.
+func calledThroughMarker(ctx context.Context) bool {
+	_, ok := ctx.Value(markerTokenKey{}).(struct{})
+	return ok
+}
+
+// AnalyzeWriter persists the last_analyze_version and
+// last_stats_histograms_version columns for a batch of physical table IDs.
+// It's the single place mysql.stats_meta's "this table was analyzed" bit
+// gets written; SaveMetaToStorage and the async histogram loader both call
+// through AnalyzeMarker instead of writing those columns directly.
+type AnalyzeWriter func(ctx context.Context, physicalIDs []int64, analyzeVersion uint64, mark bool) error
+
+// AnalyzeMarker is the only supported way to set or clear
+// last_analyze_version/last_stats_histograms_version. Routing every writer
+// (SaveMetaToStorage, DumpStatsDeltaToKV, the async stats load path) through
+// it means none of them has to re-derive whether a table "counts" as
+// analyzed; they just call MarkTableAnalyzed or UnmarkTableAnalyzed.
+type AnalyzeMarker struct {
+	write AnalyzeWriter
+}
+
+// NewAnalyzeMarker creates an AnalyzeMarker backed by write.
+func NewAnalyzeMarker(write AnalyzeWriter) *AnalyzeMarker {
+	return &AnalyzeMarker{write: write}
+}
+
+// MarkTableAnalyzed records that physicalIDs were analyzed at analyzeVersion,
+// setting both last_analyze_version and last_stats_histograms_version.
+func (m *AnalyzeMarker) MarkTableAnalyzed(ctx context.Context, physicalIDs []int64, analyzeVersion uint64) error {
+	return m.write(withMarkerToken(ctx), physicalIDs, analyzeVersion, true)
+}
+
+// UnmarkTableAnalyzed clears last_analyze_version/last_stats_histograms_version
+// for physicalIDs, e.g. when a DDL change invalidates a table's stats enough
+// that it should fall back to pseudo-stats behavior until the next analyze.
+func (m *AnalyzeMarker) UnmarkTableAnalyzed(ctx context.Context, physicalIDs []int64) error {
+	return m.write(withMarkerToken(ctx), physicalIDs, 0, false)
+}
+
+// assertCalledThroughMarker is called by every other writer of
+// last_analyze_version/last_stats_histograms_version (SaveMetaToStorage's
+// ChunkWriter when updateHistogramsVersion is set, the async histogram
+// loader) right before it writes those columns, with the same ctx it was
+// handed, so a caller that bypasses AnalyzeMarker panics under
+// EnableDebugAssertions instead of silently drifting those columns out of
+// sync with what MarkTableAnalyzed tracks. Checking ctx instead of a
+// process-wide counter means a concurrent, unrelated call through the
+// marker on another goroutine can't mask a bypass on this one.
+func assertCalledThroughMarker(ctx context.Context) {
+	if !EnableDebugAssertions {
+		return
+	}
+	if !calledThroughMarker(ctx) {
+		panic("last_analyze_version/last_stats_histograms_version written outside AnalyzeMarker")
+	}
+}
+
+// WrapChunkWriter adapts base, which is expected to persist only count and
+// modify_count, into a ChunkWriter that also routes
+// last_analyze_version/last_stats_histograms_version through marker for any
+// chunk written with updateHistogramsVersion set. This is how
+// MetaBatcher.SaveMetaToStorage avoids writing those two columns itself:
+// every chunk's "was this analyzed" bit goes through MarkTableAnalyzed, same
+// as the async histogram loader.
+func WrapChunkWriter(base ChunkWriter, marker *AnalyzeMarker) ChunkWriter {
+	return func(ctx context.Context, assignedVersion uint64, source string, updateHistogramsVersion bool, chunk []types.MetaUpdate) error {
+		if err := base(ctx, assignedVersion, source, updateHistogramsVersion, chunk); err != nil {
+			return err
+		}
+		if !updateHistogramsVersion {
+			return nil
+		}
+		physicalIDs := make([]int64, len(chunk))
+		for i, meta := range chunk {
+			physicalIDs[i] = meta.PhysicalID
+		}
+		return marker.MarkTableAnalyzed(ctx, physicalIDs, assignedVersion)
+	}
+}
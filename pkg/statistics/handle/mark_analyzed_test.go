@@ -0,0 +1,107 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handle
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pingcap/tidb/pkg/statistics/handle/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeMarkerMarkAndUnmark(t *testing.T) {
+	var gotIDs []int64
+	var gotVersion uint64
+	var gotMark bool
+	marker := NewAnalyzeMarker(func(_ context.Context, physicalIDs []int64, analyzeVersion uint64, mark bool) error {
+		gotIDs = physicalIDs
+		gotVersion = analyzeVersion
+		gotMark = mark
+		return nil
+	})
+
+	require.NoError(t, marker.MarkTableAnalyzed(context.Background(), []int64{1, 2}, 42))
+	require.Equal(t, []int64{1, 2}, gotIDs)
+	require.Equal(t, uint64(42), gotVersion)
+	require.True(t, gotMark)
+
+	require.NoError(t, marker.UnmarkTableAnalyzed(context.Background(), []int64{1, 2}))
+	require.False(t, gotMark)
+	require.Equal(t, uint64(0), gotVersion)
+}
+
+func TestAssertCalledThroughMarkerPanicsOnBypass(t *testing.T) {
+	EnableDebugAssertions = true
+	defer func() { EnableDebugAssertions = false }()
+
+	require.Panics(t, func() { assertCalledThroughMarker(context.Background()) })
+
+	marker := NewAnalyzeMarker(func(ctx context.Context, _ []int64, _ uint64, _ bool) error {
+		require.NotPanics(t, func() { assertCalledThroughMarker(ctx) })
+		return nil
+	})
+	require.NoError(t, marker.MarkTableAnalyzed(context.Background(), []int64{1}, 1))
+}
+
+// TestAssertCalledThroughMarkerConcurrentBypassNotMasked guards against the
+// process-wide-counter version of this assertion, where a concurrent,
+// legitimate call through the marker on one goroutine could make a bypass
+// on another goroutine look covered. With the ctx-scoped token, the two
+// calls can't interfere with each other.
+func TestAssertCalledThroughMarkerConcurrentBypassNotMasked(t *testing.T) {
+	EnableDebugAssertions = true
+	defer func() { EnableDebugAssertions = false }()
+
+	marker := NewAnalyzeMarker(func(context.Context, []int64, uint64, bool) error {
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			_ = marker.MarkTableAnalyzed(context.Background(), []int64{1}, 1)
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		require.Panics(t, func() { assertCalledThroughMarker(context.Background()) })
+	}
+	wg.Wait()
+}
+
+func TestWrapChunkWriterRoutesThroughMarker(t *testing.T) {
+	var baseCalled bool
+	base := func(context.Context, uint64, string, bool, []types.MetaUpdate) error {
+		baseCalled = true
+		return nil
+	}
+	var markedIDs []int64
+	marker := NewAnalyzeMarker(func(_ context.Context, physicalIDs []int64, _ uint64, mark bool) error {
+		if mark {
+			markedIDs = physicalIDs
+		}
+		return nil
+	})
+
+	wrapped := WrapChunkWriter(base, marker)
+	chunk := []types.MetaUpdate{{PhysicalID: 10}, {PhysicalID: 20}}
+	require.NoError(t, wrapped(context.Background(), 5, "test", true, chunk))
+	require.True(t, baseCalled)
+	require.Equal(t, []int64{10, 20}, markedIDs)
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package handle will eventually host StatsHandle itself; for now it only
+// carries the batching helper behind SaveMetaToStorage, since the rest of
+// the handle (domain wiring, kv transactions, the async load path) isn't
+// part of this snapshot.
+package handle
+
+import (
+	"context"
+
+	"github.com/pingcap/tidb/pkg/statistics/handle/types"
+)
+
+// DefaultStatsSaveBatchSize is the chunk size SaveMetaToStorage falls back
+// to when the tidb_stats_save_batch_size session variable hasn't set a
+// MetaBatcher.ChunkSize.
+const DefaultStatsSaveBatchSize = 200
+
+// ChunkWriter persists one chunk of MetaUpdate rows under the given,
+// already-assigned stats version. Each chunk commits independently: there is
+// no cross-chunk atomicity, so a failure partway through a large batch
+// leaves the other chunks' rows committed while SaveMetaToStorage reports
+// only the failed chunk's rows as errored, so the caller can retry just those
+// physical IDs. A ChunkWriter must not itself set
+// last_analyze_version/last_stats_histograms_version for updateHistogramsVersion
+// chunks; wrap it with WrapChunkWriter so that column still only changes
+// through AnalyzeMarker.
+type ChunkWriter func(ctx context.Context, assignedVersion uint64, source string, updateHistogramsVersion bool, chunk []types.MetaUpdate) error
+
+// MetaBatcher implements SaveMetaToStorage's chunking: it splits an
+// arbitrarily large metas slice into fixed-size chunks, assigns them all the
+// same fresh stats version, and writes each chunk through WriteChunk without
+// aborting on a chunk's failure, so a caller can retry just the rows that
+// didn't make it.
+type MetaBatcher struct {
+	// ChunkSize is the max number of MetaUpdate rows written per call to
+	// WriteChunk. Zero or negative falls back to DefaultStatsSaveBatchSize.
+	ChunkSize int
+	// NextVersion allocates the stats version assigned to a whole
+	// SaveMetaToStorage call, e.g. from the PD-backed timestamp oracle.
+	NextVersion func() uint64
+	// WriteChunk persists one chunk; see ChunkWriter.
+	WriteChunk ChunkWriter
+}
+
+// NewMetaBatcher creates a MetaBatcher.
+func NewMetaBatcher(chunkSize int, nextVersion func() uint64, writeChunk ChunkWriter) *MetaBatcher {
+	return &MetaBatcher{ChunkSize: chunkSize, NextVersion: nextVersion, WriteChunk: writeChunk}
+}
+
+// SaveMetaToStorage assigns metas a single fresh stats version, writes them
+// in chunks of at most b.ChunkSize, and returns that version along with one
+// error per meta (nil for metas whose chunk wrote successfully). It keeps
+// writing subsequent chunks even after one fails, so a transient failure in
+// the middle of a large batch doesn't block the rest from landing.
+func (b *MetaBatcher) SaveMetaToStorage(ctx context.Context, source string, updateHistogramsVersion bool, metas ...types.MetaUpdate) (assignedVersion uint64, errs []error) {
+	assignedVersion = b.NextVersion()
+	errs = make([]error, len(metas))
+
+	chunkSize := b.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultStatsSaveBatchSize
+	}
+
+	for start := 0; start < len(metas); start += chunkSize {
+		end := start + chunkSize
+		if end > len(metas) {
+			end = len(metas)
+		}
+		if err := b.WriteChunk(ctx, assignedVersion, source, updateHistogramsVersion, metas[start:end]); err != nil {
+			for i := start; i < end; i++ {
+				errs[i] = err
+			}
+		}
+	}
+	return assignedVersion, errs
+}
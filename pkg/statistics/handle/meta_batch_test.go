@@ -0,0 +1,65 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handle
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pingcap/tidb/pkg/statistics/handle/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaBatcherChunksAndAssignsOneVersion(t *testing.T) {
+	var gotChunks [][]types.MetaUpdate
+	var gotVersions []uint64
+	writeChunk := func(_ context.Context, version uint64, _ string, _ bool, chunk []types.MetaUpdate) error {
+		gotVersions = append(gotVersions, version)
+		gotChunks = append(gotChunks, chunk)
+		return nil
+	}
+	b := NewMetaBatcher(2, func() uint64 { return 7 }, writeChunk)
+
+	metas := []types.MetaUpdate{{PhysicalID: 1}, {PhysicalID: 2}, {PhysicalID: 3}}
+	version, errs := b.SaveMetaToStorage(context.Background(), "test", false, metas...)
+
+	require.Equal(t, uint64(7), version)
+	require.Equal(t, []error{nil, nil, nil}, errs)
+	require.Len(t, gotChunks, 2)
+	require.Len(t, gotChunks[0], 2)
+	require.Len(t, gotChunks[1], 1)
+	require.Equal(t, []uint64{7, 7}, gotVersions)
+}
+
+func TestMetaBatcherContinuesAfterChunkError(t *testing.T) {
+	boom := errors.New("boom")
+	calls := 0
+	writeChunk := func(_ context.Context, _ uint64, _ string, _ bool, chunk []types.MetaUpdate) error {
+		calls++
+		if calls == 1 {
+			return boom
+		}
+		return nil
+	}
+	b := NewMetaBatcher(1, func() uint64 { return 1 }, writeChunk)
+
+	metas := []types.MetaUpdate{{PhysicalID: 1}, {PhysicalID: 2}}
+	_, errs := b.SaveMetaToStorage(context.Background(), "test", false, metas...)
+
+	require.Equal(t, 2, calls)
+	require.Equal(t, boom, errs[0])
+	require.NoError(t, errs[1])
+}
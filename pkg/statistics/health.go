@@ -0,0 +1,142 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+// HealthEstimatorKind selects how `SHOW STATS_HEALTHY` and the
+// tidb_enable_pseudo_for_outdated_stats decision path derive a table's
+// health score.
+type HealthEstimatorKind int
+
+const (
+	// HealthEstimatorRowCountOnly reproduces today's behavior: health is
+	// purely 100 * (1 - modifyCount/count).
+	HealthEstimatorRowCountOnly HealthEstimatorKind = iota
+	// HealthEstimatorBlended additionally factors in DriftScore, so a table
+	// that's had many rows touched but whose value distribution hasn't
+	// actually shifted (e.g. re-inserting the same handful of values) isn't
+	// marked unhealthy as aggressively.
+	HealthEstimatorBlended
+)
+
+// ValueCount is one observed value and how many times it occurred, used by
+// both the on-demand sample and the stored TopN/histogram buckets that
+// DriftScore compares against.
+type ValueCount struct {
+	Value string
+	Count int64
+}
+
+// RowDeltaHealth is the existing health signal: the fraction of rows in a
+// table that are believed to still match the last-analyzed stats, as a
+// percentage in [0, 100].
+func RowDeltaHealth(modifyCount, count int64) float64 {
+	if count == 0 {
+		if modifyCount == 0 {
+			return 100
+		}
+		return 0
+	}
+	health := 100 * (1 - float64(modifyCount)/float64(count))
+	if health < 0 {
+		return 0
+	}
+	return health
+}
+
+// DriftScore compares a freshly sampled value distribution against the
+// stored TopN/histogram-bucket distribution using a chi-squared-style
+// statistic, and returns it rescaled to [0, 100] health points (100 meaning
+// no detected drift). sample and stored are both frequency tables over the
+// same value space; values present in one but not the other are treated as
+// having zero count in the missing table.
+func DriftScore(sample, stored []ValueCount) float64 {
+	sampleTotal, storedTotal := sumCounts(sample), sumCounts(stored)
+	if sampleTotal == 0 || storedTotal == 0 {
+		// Nothing to compare against; assume no drift rather than
+		// penalizing a table whose stored stats simply have no TopN.
+		return 100
+	}
+
+	storedFreq := make(map[string]float64, len(stored))
+	for _, vc := range stored {
+		storedFreq[vc.Value] = float64(vc.Count) / float64(storedTotal)
+	}
+
+	var chiSq float64
+	seen := make(map[string]bool, len(sample))
+	for _, vc := range sample {
+		seen[vc.Value] = true
+		observed := float64(vc.Count)
+		expected := storedFreq[vc.Value] * float64(sampleTotal)
+		chiSq += chiSquaredTerm(observed, expected)
+	}
+	// Values the stored distribution expects but the sample didn't see at
+	// all also contribute to the statistic, since their absence is itself a
+	// drift signal.
+	for value, freq := range storedFreq {
+		if seen[value] {
+			continue
+		}
+		expected := freq * float64(sampleTotal)
+		chiSq += chiSquaredTerm(0, expected)
+	}
+
+	// Rescale: a chi-squared statistic of 0 is perfect health; clamp the
+	// statistic at sampleTotal (complete mismatch) before rescaling so one
+	// wildly divergent value can't blow the score below 0.
+	if chiSq > float64(sampleTotal) {
+		chiSq = float64(sampleTotal)
+	}
+	return 100 * (1 - chiSq/float64(sampleTotal))
+}
+
+func chiSquaredTerm(observed, expected float64) float64 {
+	if expected <= 0 {
+		if observed == 0 {
+			return 0
+		}
+		// No expectation at all for a value the sample actually saw: treat
+		// it as maximally surprising rather than dividing by zero.
+		return observed
+	}
+	diff := observed - expected
+	return diff * diff / expected
+}
+
+func sumCounts(vcs []ValueCount) int64 {
+	var total int64
+	for _, vc := range vcs {
+		total += vc.Count
+	}
+	return total
+}
+
+// BlendedHealth combines RowDeltaHealth and DriftScore into a single score
+// per kind: HealthEstimatorRowCountOnly ignores drift entirely, preserving
+// today's behavior, while HealthEstimatorBlended takes a weighted average
+// controlled by rowDeltaWeight (in [0, 1]), so a high row-delta table whose
+// distribution hasn't drifted still reads as healthier than a pure
+// row-count estimate would suggest.
+func BlendedHealth(kind HealthEstimatorKind, rowDeltaHealth, driftHealth, rowDeltaWeight float64) float64 {
+	if kind == HealthEstimatorRowCountOnly {
+		return rowDeltaHealth
+	}
+	if rowDeltaWeight < 0 {
+		rowDeltaWeight = 0
+	} else if rowDeltaWeight > 1 {
+		rowDeltaWeight = 1
+	}
+	return rowDeltaWeight*rowDeltaHealth + (1-rowDeltaWeight)*driftHealth
+}
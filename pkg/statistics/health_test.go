@@ -0,0 +1,45 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowDeltaHealth(t *testing.T) {
+	require.Equal(t, float64(100), RowDeltaHealth(0, 100))
+	require.Equal(t, float64(50), RowDeltaHealth(50, 100))
+	require.Equal(t, float64(0), RowDeltaHealth(150, 100))
+	require.Equal(t, float64(100), RowDeltaHealth(0, 0))
+}
+
+func TestDriftScoreNoDrift(t *testing.T) {
+	stored := []ValueCount{{Value: "a", Count: 80}, {Value: "b", Count: 20}}
+	sample := []ValueCount{{Value: "a", Count: 40}, {Value: "b", Count: 10}}
+	require.InDelta(t, 100, DriftScore(sample, stored), 1e-6)
+}
+
+func TestDriftScoreWithDrift(t *testing.T) {
+	stored := []ValueCount{{Value: "a", Count: 100}}
+	sample := []ValueCount{{Value: "b", Count: 100}}
+	require.Less(t, DriftScore(sample, stored), float64(50))
+}
+
+func TestBlendedHealth(t *testing.T) {
+	require.Equal(t, float64(40), BlendedHealth(HealthEstimatorRowCountOnly, 40, 90, 0.5))
+	require.InDelta(t, 65, BlendedHealth(HealthEstimatorBlended, 40, 90, 0.5), 1e-6)
+}
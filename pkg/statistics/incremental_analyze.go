@@ -0,0 +1,124 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import "sort"
+
+// RowIDRange is a half-open range of row handles [Start, End) touched since
+// a partition's stats were last built, as tracked via the delta accounting
+// DumpStatsDeltaToKV already maintains.
+type RowIDRange struct {
+	Start, End int64
+}
+
+// rowCount is the number of row handles covered by r.
+func (r RowIDRange) rowCount() int64 {
+	if r.End <= r.Start {
+		return 0
+	}
+	return r.End - r.Start
+}
+
+// TouchedRanges is the set of row ranges touched on one partition since its
+// stats were last built, which an incremental ("merge-in-place") analyze
+// samples instead of rescanning the whole partition.
+type TouchedRanges struct {
+	PhysicalID int64
+	Ranges     []RowIDRange
+}
+
+// TouchedRowCount sums the row count covered by t's ranges.
+func (t TouchedRanges) TouchedRowCount() int64 {
+	var total int64
+	for _, r := range t.Ranges {
+		total += r.rowCount()
+	}
+	return total
+}
+
+// IncrementalPlan is the outcome of PlanIncrementalAnalyze: which partitions
+// `ANALYZE TABLE t INCREMENTAL` can merge in place, and which still need a
+// full rescan.
+type IncrementalPlan struct {
+	// FullRescan lists partitions that must still be scanned in full, e.g.
+	// because they have no prior stats or have drifted past
+	// maxTouchedFraction.
+	FullRescan []int64
+	// Incremental lists partitions that can be merged in place from just
+	// their TouchedRanges.
+	Incremental []int64
+}
+
+// PlanIncrementalAnalyze decides, per partition, whether a merge-in-place
+// analyze suffices or a full rescan is required, based on the fraction of
+// rows touched since the partition's stats were last built. It's also how
+// auto-analyze picks between the two strategies when a table's health falls
+// between the configured full/incremental thresholds: callers only include
+// partitions in that band in touched.
+func PlanIncrementalAnalyze(touched []TouchedRanges, totalRows map[int64]int64, hasStats map[int64]bool, maxTouchedFraction float64) IncrementalPlan {
+	var plan IncrementalPlan
+	for _, t := range touched {
+		total := totalRows[t.PhysicalID]
+		if !hasStats[t.PhysicalID] || total <= 0 {
+			plan.FullRescan = append(plan.FullRescan, t.PhysicalID)
+			continue
+		}
+		fraction := float64(t.TouchedRowCount()) / float64(total)
+		if fraction > maxTouchedFraction {
+			plan.FullRescan = append(plan.FullRescan, t.PhysicalID)
+			continue
+		}
+		plan.Incremental = append(plan.Incremental, t.PhysicalID)
+	}
+	return plan
+}
+
+// MergeTopN merges TopN entries freshly sampled from a partition's
+// TouchedRanges into its existing persisted TopN, summing counts for values
+// present in both and keeping the overall top maxEntries by count. This
+// only holds up for version-2 stats, whose TopN and CM-sketch are
+// mergeable. PlanIncrementalAnalyze takes no stats version, so it does not
+// itself send version-1 partitions to a full rescan.
+func MergeTopN(existing, fresh []ValueCount, maxEntries int) []ValueCount {
+	counts := make(map[string]int64, len(existing)+len(fresh))
+	order := make([]string, 0, len(existing)+len(fresh))
+	for _, vc := range existing {
+		if _, ok := counts[vc.Value]; !ok {
+			order = append(order, vc.Value)
+		}
+		counts[vc.Value] += vc.Count
+	}
+	for _, vc := range fresh {
+		if _, ok := counts[vc.Value]; !ok {
+			order = append(order, vc.Value)
+		}
+		counts[vc.Value] += vc.Count
+	}
+
+	merged := make([]ValueCount, 0, len(order))
+	for _, v := range order {
+		merged = append(merged, ValueCount{Value: v, Count: counts[v]})
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Count != merged[j].Count {
+			return merged[i].Count > merged[j].Count
+		}
+		return merged[i].Value < merged[j].Value
+	})
+	if len(merged) > maxEntries {
+		merged = merged[:maxEntries]
+	}
+	return merged
+}
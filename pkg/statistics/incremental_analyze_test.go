@@ -0,0 +1,43 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanIncrementalAnalyze(t *testing.T) {
+	touched := []TouchedRanges{
+		{PhysicalID: 1, Ranges: []RowIDRange{{Start: 0, End: 100}}},
+		{PhysicalID: 2, Ranges: []RowIDRange{{Start: 0, End: 900}}},
+		{PhysicalID: 3, Ranges: []RowIDRange{{Start: 0, End: 10}}},
+	}
+	totalRows := map[int64]int64{1: 1000, 2: 1000, 3: 1000}
+	hasStats := map[int64]bool{1: true, 2: true, 3: false}
+
+	plan := PlanIncrementalAnalyze(touched, totalRows, hasStats, 0.5)
+	require.ElementsMatch(t, []int64{1}, plan.Incremental)
+	require.ElementsMatch(t, []int64{2, 3}, plan.FullRescan)
+}
+
+func TestMergeTopN(t *testing.T) {
+	existing := []ValueCount{{Value: "a", Count: 50}, {Value: "b", Count: 30}}
+	fresh := []ValueCount{{Value: "a", Count: 5}, {Value: "c", Count: 40}}
+
+	merged := MergeTopN(existing, fresh, 2)
+	require.Equal(t, []ValueCount{{Value: "a", Count: 55}, {Value: "c", Count: 40}}, merged)
+}
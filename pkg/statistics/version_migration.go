@@ -0,0 +1,116 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import "sync"
+
+// MigrationState is the lifecycle of a VersionMigration.
+type MigrationState int
+
+const (
+	// MigrationInProgress means the target version's stats are being built
+	// (e.g. a fresh sample for TopN/CM-sketch) while the old version is
+	// still what queries load.
+	MigrationInProgress MigrationState = iota
+	// MigrationComplete means the target version has been fully persisted
+	// and is now what ActiveVersion reports; the old version's stats are
+	// retained only until the next analyze overwrites them.
+	MigrationComplete
+)
+
+// VersionMigration tracks an in-flight migration of one table's stats from
+// one analyze version to another (e.g. `ANALYZE TABLE t UPGRADE VERSION
+// 2`). Unlike the old "version incompatible" fallback, which silently kept
+// the table on its original version, both versions stay loadable for the
+// duration of the migration, and queries only switch once State is
+// MigrationComplete.
+type VersionMigration struct {
+	TableID     int64
+	FromVersion int
+	ToVersion   int
+	State       MigrationState
+}
+
+// Complete marks the migration as finished, so ActiveVersion starts
+// reporting ToVersion.
+func (m *VersionMigration) Complete() {
+	m.State = MigrationComplete
+}
+
+// ActiveVersion returns which version queries should load for this table:
+// FromVersion while the migration is in progress, ToVersion once complete.
+func (m *VersionMigration) ActiveVersion() int {
+	if m.State == MigrationComplete {
+		return m.ToVersion
+	}
+	return m.FromVersion
+}
+
+// Migrator tracks the VersionMigration in flight for every table being
+// migrated, so whoever drives a migration can report its progress and
+// the load path can look up which version a table is currently serving.
+type Migrator struct {
+	mu      sync.RWMutex
+	byTable map[int64]*VersionMigration
+}
+
+// NewMigrator creates an empty Migrator.
+func NewMigrator() *Migrator {
+	return &Migrator{byTable: make(map[int64]*VersionMigration)}
+}
+
+// Start begins migrating tableID from fromVersion to toVersion, replacing
+// any migration already in flight for it.
+func (m *Migrator) Start(tableID int64, fromVersion, toVersion int) *VersionMigration {
+	migration := &VersionMigration{
+		TableID:     tableID,
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		State:       MigrationInProgress,
+	}
+	m.mu.Lock()
+	m.byTable[tableID] = migration
+	m.mu.Unlock()
+	return migration
+}
+
+// Complete marks tableID's migration as finished, if one is in flight.
+func (m *Migrator) Complete(tableID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if migration, ok := m.byTable[tableID]; ok {
+		migration.Complete()
+	}
+}
+
+// Forget removes tableID's migration bookkeeping once its result has been
+// fully persisted and no longer needs tracking, e.g. after the next
+// successful analyze.
+func (m *Migrator) Forget(tableID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.byTable, tableID)
+}
+
+// ActiveVersion returns which version tableID's queries should load: the
+// result of any in-flight VersionMigration, or fallback if none is tracked.
+func (m *Migrator) ActiveVersion(tableID int64, fallback int) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if migration, ok := m.byTable[tableID]; ok {
+		return migration.ActiveVersion()
+	}
+	return fallback
+}
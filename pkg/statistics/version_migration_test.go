@@ -0,0 +1,42 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionMigrationActiveVersion(t *testing.T) {
+	m := &VersionMigration{TableID: 1, FromVersion: 1, ToVersion: 2, State: MigrationInProgress}
+	require.Equal(t, 1, m.ActiveVersion())
+	m.Complete()
+	require.Equal(t, 2, m.ActiveVersion())
+}
+
+func TestMigratorLifecycle(t *testing.T) {
+	m := NewMigrator()
+	require.Equal(t, 1, m.ActiveVersion(42, 1))
+
+	m.Start(42, 1, 2)
+	require.Equal(t, 1, m.ActiveVersion(42, 1))
+
+	m.Complete(42)
+	require.Equal(t, 2, m.ActiveVersion(42, 1))
+
+	m.Forget(42)
+	require.Equal(t, 1, m.ActiveVersion(42, 1))
+}
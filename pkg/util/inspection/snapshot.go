@@ -0,0 +1,113 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inspection implements the archive format behind
+// `ADMIN DUMP INSPECTION SNAPSHOT` / `ADMIN LOAD INSPECTION SNAPSHOT`: a
+// single self-describing file that freezes the cluster_*/inspection_*/
+// metrics_*/TIFLASH_* virtual tables so support engineers can reproduce a
+// customer's information_schema state without live cluster access. The SQL
+// statements themselves are parsed and executed elsewhere; this package only
+// owns the archive's shape and its encode/decode.
+package inspection
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TableSnapshot is the frozen row set for one virtual table, in the same
+// shape variable.TableSnapshot expects so a restored session can serve
+// queries against it without touching a live cluster.
+type TableSnapshot struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Manifest describes an Archive without needing to decode every table,
+// so tooling can validate compatibility before loading the full payload.
+type Manifest struct {
+	// TiDBVersion is the version string of the cluster the snapshot was
+	// taken from, e.g. the output of `SELECT tidb_version()`.
+	TiDBVersion string `json:"tidb_version"`
+	// Topology lists the cluster's components (tidb/tikv/pd/tiflash) and
+	// their addresses, as seen in cluster_info at dump time.
+	Topology []TopologyNode `json:"topology"`
+	// Tables lists the table names included in the archive, in
+	// `SCHEMA.TABLE` form, e.g. "information_schema.TIFLASH_SEGMENTS".
+	Tables []string `json:"tables"`
+	// CreatedAt is when the archive was produced.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TopologyNode is one entry of Manifest.Topology.
+type TopologyNode struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Version string `json:"version"`
+}
+
+// Archive is the full contents of an inspection snapshot file: a Manifest
+// plus every table it describes, keyed the same way as Manifest.Tables.
+type Archive struct {
+	Manifest Manifest                  `json:"manifest"`
+	Tables   map[string]*TableSnapshot `json:"tables"`
+}
+
+// NewArchive creates an empty Archive for tidbVersion/topology, ready to
+// have tables added via Put.
+func NewArchive(tidbVersion string, topology []TopologyNode, createdAt time.Time) *Archive {
+	return &Archive{
+		Manifest: Manifest{
+			TiDBVersion: tidbVersion,
+			Topology:    topology,
+			CreatedAt:   createdAt,
+		},
+		Tables: make(map[string]*TableSnapshot),
+	}
+}
+
+// Put adds or replaces the snapshot for a `schema.table`, keeping
+// Manifest.Tables in sync.
+func (a *Archive) Put(schemaDotTable string, snap *TableSnapshot) {
+	if _, exists := a.Tables[schemaDotTable]; !exists {
+		a.Manifest.Tables = append(a.Manifest.Tables, schemaDotTable)
+	}
+	a.Tables[schemaDotTable] = snap
+}
+
+// Dump encodes the archive as a single JSON document to w. The manifest is
+// embedded rather than split out, since inspection snapshots are meant to be
+// a self-contained file support engineers can hand off directly.
+func Dump(w io.Writer, archive *Archive) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(archive)
+}
+
+// Load decodes an archive previously written by Dump and validates that
+// every table named in the manifest has a matching payload.
+func Load(r io.Reader) (*Archive, error) {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("decode inspection snapshot: %w", err)
+	}
+	for _, name := range archive.Manifest.Tables {
+		if _, ok := archive.Tables[name]; !ok {
+			return nil, fmt.Errorf("inspection snapshot manifest references table %q with no payload", name)
+		}
+	}
+	return &archive, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiflashsystable
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheMetrics reports the hit/miss counters of a CachingClient, for
+// exporting via the TIFLASH_SYSTEM_TABLE_CACHE view.
+type CacheMetrics struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// cacheEntry is one cached (storeAddr, sql) response.
+type cacheEntry struct {
+	schema    []RawColumn
+	rows      [][]string
+	err       error
+	fetchedAt time.Time
+}
+
+// inflight tracks a request already being fetched, so concurrent callers
+// for the same (storeAddr, sql) coalesce onto one RPC instead of each
+// issuing their own.
+type inflight struct {
+	done chan struct{}
+	entry cacheEntry
+}
+
+// CachingClient wraps a SystemTableClient with a TTL cache keyed by
+// (storeAddr, sql): concurrent identical requests are coalesced onto a
+// single in-flight RPC, and a completed response is reused for ttl before
+// it's fetched again. This exists because every information_schema query
+// touching TIFLASH_* tables otherwise issues one CmdGetTiFlashSystemTable
+// RPC per (store, SQL) pair, which gets expensive on large clusters.
+type CachingClient struct {
+	inner SystemTableClient
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	cache     map[string]cacheEntry
+	inFlights map[string]*inflight
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewCachingClient wraps inner with a cache whose entries expire after ttl.
+// A ttl of 0 disables caching: every call is coalesced against any identical
+// in-flight request but never reused afterward.
+func NewCachingClient(inner SystemTableClient, ttl time.Duration) *CachingClient {
+	return &CachingClient{
+		inner:     inner,
+		ttl:       ttl,
+		cache:     make(map[string]cacheEntry),
+		inFlights: make(map[string]*inflight),
+	}
+}
+
+func cacheKey(storeAddr, sql string) string {
+	return storeAddr + "\x00" + sql
+}
+
+// QuerySystemTable implements SystemTableClient.
+func (c *CachingClient) QuerySystemTable(ctx context.Context, storeAddr, sql string) ([]RawColumn, [][]string, error) {
+	key := cacheKey(storeAddr, sql)
+
+	c.mu.Lock()
+	if entry, ok := c.cache[key]; ok && c.ttl > 0 && time.Since(entry.fetchedAt) < c.ttl {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return entry.schema, entry.rows, entry.err
+	}
+	if inf, ok := c.inFlights[key]; ok {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		<-inf.done
+		return inf.entry.schema, inf.entry.rows, inf.entry.err
+	}
+	inf := &inflight{done: make(chan struct{})}
+	c.inFlights[key] = inf
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	schema, rows, err := c.inner.QuerySystemTable(ctx, storeAddr, sql)
+
+	c.mu.Lock()
+	inf.entry = cacheEntry{schema: schema, rows: rows, err: err, fetchedAt: time.Now()}
+	if c.ttl > 0 {
+		c.cache[key] = inf.entry
+	}
+	delete(c.inFlights, key)
+	c.mu.Unlock()
+	close(inf.done)
+
+	return schema, rows, err
+}
+
+// Invalidate drops every cached entry for storeAddr, for callers that tie
+// cache invalidation to placement-rule or DDL events affecting that store.
+func (c *CachingClient) Invalidate(storeAddr string) {
+	prefix := storeAddr + "\x00"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.cache {
+		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+			delete(c.cache, key)
+		}
+	}
+}
+
+// Metrics returns the cache's hit/miss counters.
+func (c *CachingClient) Metrics() CacheMetrics {
+	return CacheMetrics{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+var _ SystemTableClient = (*CachingClient)(nil)
@@ -0,0 +1,70 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiflashsystable
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingClient struct {
+	calls atomic.Int64
+}
+
+func (c *countingClient) QuerySystemTable(_ context.Context, _, _ string) ([]RawColumn, [][]string, error) {
+	c.calls.Add(1)
+	return []RawColumn{{Name: "database", Type: ColumnTypeString}}, [][]string{{"test"}}, nil
+}
+
+func TestCachingClientReusesWithinTTL(t *testing.T) {
+	inner := &countingClient{}
+	client := NewCachingClient(inner, time.Minute)
+
+	for range 5 {
+		_, rows, err := client.QuerySystemTable(context.Background(), "store1", "select 1")
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"test"}}, rows)
+	}
+	require.EqualValues(t, 1, inner.calls.Load())
+	require.Equal(t, CacheMetrics{Hits: 4, Misses: 1}, client.Metrics())
+}
+
+func TestCachingClientExpiresAfterTTL(t *testing.T) {
+	inner := &countingClient{}
+	client := NewCachingClient(inner, time.Nanosecond)
+
+	_, _, err := client.QuerySystemTable(context.Background(), "store1", "select 1")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, _, err = client.QuerySystemTable(context.Background(), "store1", "select 1")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, inner.calls.Load())
+}
+
+func TestCachingClientInvalidate(t *testing.T) {
+	inner := &countingClient{}
+	client := NewCachingClient(inner, time.Minute)
+
+	_, _, err := client.QuerySystemTable(context.Background(), "store1", "select 1")
+	require.NoError(t, err)
+	client.Invalidate("store1")
+	_, _, err = client.QuerySystemTable(context.Background(), "store1", "select 1")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, inner.calls.Load())
+}
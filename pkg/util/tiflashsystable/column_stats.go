@@ -0,0 +1,196 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiflashsystable
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ColumnStatsTable is the `system` table queried for
+// information_schema.TIFLASH_COLUMN_STATS.
+const ColumnStatsTable = "dt_column_stats"
+
+// ColumnStatsRow is one row of information_schema.TIFLASH_COLUMN_STATS,
+// sourced from a TiFlash instance's delta-merge columnar metadata.
+type ColumnStatsRow struct {
+	TableSchema       string
+	TableName         string
+	ColumnName        string
+	ColumnID          int64
+	StableRows        int64
+	DeltaRows         int64
+	CompressedBytes   int64
+	UncompressedBytes int64
+	NullCount         int64
+	Min               string
+	Max               string
+	// InstanceAddr is the TiFlash store address the row was fetched from.
+	InstanceAddr string
+	// ExtraColumnsJSON holds any dt_column_stats columns this TiDB version
+	// doesn't know how to map onto the columns above; see DecodeRow.
+	ExtraColumnsJSON string
+}
+
+// SystemTableClient issues one CmdGetTiFlashSystemTable-style request to a
+// TiFlash instance and returns the raw rows it reported. It's the seam
+// getTiFlashSystemTableRequestMocker substitutes a fake implementation for
+// in tests.
+type SystemTableClient interface {
+	QuerySystemTable(ctx context.Context, storeAddr, sql string) (schema []RawColumn, rows [][]string, err error)
+}
+
+// columnStatsKnownColumns maps TIFLASH_COLUMN_STATS' fixed columns onto the
+// dt_column_stats column a current TiFlash store reports for them. A store
+// running an older version may report a renamed or missing column for one
+// of these; resolveKnownColumns runs each entry through StoreSchema.Resolve
+// against that store's actually-reported columns instead of assuming this
+// map applies verbatim everywhere.
+var columnStatsKnownColumns = map[string]string{
+	"TABLE_SCHEMA":       "database",
+	"TABLE_NAME":         "table",
+	"COLUMN_NAME":        "column_name",
+	"COLUMN_ID":          "column_id",
+	"STABLE_ROWS":        "stable_rows",
+	"DELTA_ROWS":         "delta_rows",
+	"COMPRESSED_BYTES":   "compressed_bytes",
+	"UNCOMPRESSED_BYTES": "uncompressed_bytes",
+	"NULL_COUNT":         "null_count",
+	"MIN_VALUE":          "min",
+	"MAX_VALUE":          "max",
+}
+
+// columnStatsNegotiator caches each store's negotiated StoreSchema for
+// dt_column_stats, keyed by a fingerprint of the columns it reported, so
+// concurrent fetches against a store that hasn't changed columns don't each
+// rebuild the same StoreSchema.
+var columnStatsNegotiator = NewNegotiator()
+
+// schemaFingerprint derives a Negotiator version key from the columns a
+// store reported for this query. Unlike the segments/tables readers,
+// FetchColumnStats has no separate cluster_info RPC to read an actual
+// TiFlash version from, but the reported column list only changes across a
+// TiFlash upgrade, so it serves the same purpose: a rename invalidates the
+// cached StoreSchema instead of silently reusing a stale one.
+func schemaFingerprint(schema []RawColumn) string {
+	names := make([]string, len(schema))
+	for i, col := range schema {
+		names[i] = col.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// resolveKnownColumns builds DecodeRow's known map for one store by running
+// columnStatsKnownColumns through StoreSchema.Resolve, so a store reporting
+// a legacy dt_column_stats column name still gets that column mapped onto
+// its TIFLASH_COLUMN_STATS column instead of falling into ExtraColumnsJSON,
+// and a store genuinely missing a column simply omits it from known rather
+// than mapping it to a column DecodeRow won't find in schema.
+func resolveKnownColumns(schema StoreSchema) map[string]string {
+	known := make(map[string]string, len(columnStatsKnownColumns))
+	for tidbCol, storeCol := range columnStatsKnownColumns {
+		if resolved, ok := schema.Resolve(ColumnStatsTable, storeCol); ok {
+			known[tidbCol] = resolved
+		}
+	}
+	return known
+}
+
+// storeResult is one store's fan-out outcome, kept separate from errors so a
+// single failing instance doesn't drop every other instance's rows.
+type storeResult struct {
+	storeAddr string
+	rows      []ColumnStatsRow
+	err       error
+}
+
+// FetchColumnStats fans the `SELECT * FROM system.dt_column_stats LIMIT 0,
+// 1024` query (or equivalent, built via BuildSelectColumns when explicit
+// columns are enabled) out to every store in storeAddrs in parallel, using
+// the same per-instance error handling as the segments/tables readers: a
+// failing store's error is returned alongside whatever other stores
+// succeeded, rather than aborting the whole query.
+func FetchColumnStats(ctx context.Context, client SystemTableClient, storeAddrs []string, sql string) ([]ColumnStatsRow, map[string]error) {
+	results := make(chan storeResult, len(storeAddrs))
+	var wg sync.WaitGroup
+	for _, addr := range storeAddrs {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			schema, rawRows, err := client.QuerySystemTable(ctx, addr, sql)
+			if err != nil {
+				results <- storeResult{storeAddr: addr, err: err}
+				return
+			}
+			version := schemaFingerprint(schema)
+			storeSchema, ok := columnStatsNegotiator.Get(addr, ColumnStatsTable, version)
+			if !ok {
+				columns := make([]string, len(schema))
+				for i, col := range schema {
+					columns[i] = col.Name
+				}
+				columnStatsNegotiator.Put(addr, ColumnStatsTable, version, columns)
+				storeSchema, _ = columnStatsNegotiator.Get(addr, ColumnStatsTable, version)
+			}
+			known := resolveKnownColumns(storeSchema)
+			rows := make([]ColumnStatsRow, 0, len(rawRows))
+			for _, raw := range rawRows {
+				mapped, extra, decodeErr := DecodeRow(schema, raw, known)
+				if decodeErr != nil {
+					results <- storeResult{storeAddr: addr, err: decodeErr}
+					return
+				}
+				rows = append(rows, columnStatsRowFromMap(addr, mapped, extra))
+			}
+			results <- storeResult{storeAddr: addr, rows: rows}
+		}(addr)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var allRows []ColumnStatsRow
+	errs := make(map[string]error)
+	for res := range results {
+		if res.err != nil {
+			errs[res.storeAddr] = res.err
+			continue
+		}
+		allRows = append(allRows, res.rows...)
+	}
+	return allRows, errs
+}
+
+func columnStatsRowFromMap(storeAddr string, mapped map[string]string, extraJSON string) ColumnStatsRow {
+	return ColumnStatsRow{
+		TableSchema:       mapped["TABLE_SCHEMA"],
+		TableName:         mapped["TABLE_NAME"],
+		ColumnName:        mapped["COLUMN_NAME"],
+		ColumnID:          parseInt64(mapped["COLUMN_ID"]),
+		StableRows:        parseInt64(mapped["STABLE_ROWS"]),
+		DeltaRows:         parseInt64(mapped["DELTA_ROWS"]),
+		CompressedBytes:   parseInt64(mapped["COMPRESSED_BYTES"]),
+		UncompressedBytes: parseInt64(mapped["UNCOMPRESSED_BYTES"]),
+		NullCount:         parseInt64(mapped["NULL_COUNT"]),
+		Min:               mapped["MIN_VALUE"],
+		Max:               mapped["MAX_VALUE"],
+		InstanceAddr:      storeAddr,
+		ExtraColumnsJSON:  extraJSON,
+	}
+}
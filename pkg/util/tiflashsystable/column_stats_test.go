@@ -0,0 +1,70 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiflashsystable
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockSystemTableClient struct {
+	schema []RawColumn
+	rows   map[string][][]string
+	errs   map[string]error
+}
+
+func (m *mockSystemTableClient) QuerySystemTable(_ context.Context, storeAddr, _ string) ([]RawColumn, [][]string, error) {
+	if err, ok := m.errs[storeAddr]; ok {
+		return nil, nil, err
+	}
+	return m.schema, m.rows[storeAddr], nil
+}
+
+func TestFetchColumnStats(t *testing.T) {
+	schema := []RawColumn{
+		{Name: "database", Type: ColumnTypeString},
+		{Name: "table", Type: ColumnTypeString},
+		{Name: "column_name", Type: ColumnTypeString},
+		{Name: "column_id", Type: ColumnTypeInt64},
+		{Name: "stable_rows", Type: ColumnTypeInt64},
+		{Name: "new_in_v7", Type: ColumnTypeFloat64},
+	}
+	client := &mockSystemTableClient{
+		schema: schema,
+		rows: map[string][][]string{
+			"store1": {{"test", "t1", "col1", "1", "100", "3.5"}},
+			"store2": {{"test", "t1", "col2", "2", "200", "4.5"}},
+		},
+		errs: map[string]error{
+			"store3": errors.New("unreachable"),
+		},
+	}
+
+	rows, errs := FetchColumnStats(context.Background(), client, []string{"store1", "store2", "store3"}, "select * from system.dt_column_stats limit 0, 1024")
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs["store3"], "unreachable")
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].InstanceAddr < rows[j].InstanceAddr })
+	require.Len(t, rows, 2)
+	require.Equal(t, "col1", rows[0].ColumnName)
+	require.Equal(t, int64(100), rows[0].StableRows)
+	require.Equal(t, "store1", rows[0].InstanceAddr)
+	require.JSONEq(t, `{"new_in_v7": 3.5}`, rows[0].ExtraColumnsJSON)
+	require.Equal(t, "store2", rows[1].InstanceAddr)
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiflashsystable
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// ColumnType is the subset of TiFlash-reported column types the decoder
+// knows how to coerce a raw string value into a typed one for extra_columns.
+type ColumnType string
+
+// TiFlash's CmdGetTiFlashSystemTable response reports column types as plain
+// strings; these are the ones observed in practice.
+const (
+	ColumnTypeInt64   ColumnType = "Int64"
+	ColumnTypeFloat64 ColumnType = "Float64"
+	ColumnTypeString  ColumnType = "String"
+)
+
+// RawColumn is one entry of a TiFlashSystemTableResponse's `schema[]`: the
+// column name and type TiFlash reported for a row's positional value.
+type RawColumn struct {
+	Name string
+	Type ColumnType
+}
+
+// DecodeRow maps a TiFlash row's raw column/value pairs onto TiDB's known
+// columns for table, returning the values keyed by TiDB column name plus a
+// JSON object of every column TiDB doesn't recognize, so newer TiFlash
+// versions that add columns don't silently drop them.
+//
+// known maps a TiDB-facing column name to the store column that currently
+// backs it, as produced by StoreSchema.Resolve; values are looked up by
+// reversing that mapping against schema/values.
+func DecodeRow(schema []RawColumn, values []string, known map[string]string) (mapped map[string]string, extraColumnsJSON string, err error) {
+	storeToTiDB := make(map[string]string, len(known))
+	for tidbCol, storeCol := range known {
+		storeToTiDB[storeCol] = tidbCol
+	}
+
+	mapped = make(map[string]string, len(known))
+	extra := make(map[string]any)
+	for i, col := range schema {
+		if i >= len(values) {
+			break
+		}
+		raw := values[i]
+		if tidbCol, ok := storeToTiDB[col.Name]; ok {
+			mapped[tidbCol] = raw
+			continue
+		}
+		extra[col.Name] = coerce(col.Type, raw)
+	}
+
+	if len(extra) == 0 {
+		return mapped, "", nil
+	}
+	b, err := json.Marshal(extra)
+	if err != nil {
+		return mapped, "", err
+	}
+	return mapped, string(b), nil
+}
+
+// parseInt64 parses raw as a base-10 int64, returning 0 for an empty or
+// malformed value rather than failing the whole row.
+func parseInt64(raw string) int64 {
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// coerce converts a raw string value to the Go type that best matches
+// typ, falling back to the raw string when it doesn't parse, so a single
+// malformed value can't fail decoding of the whole row.
+func coerce(typ ColumnType, raw string) any {
+	switch typ {
+	case ColumnTypeInt64:
+		if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return v
+		}
+	case ColumnTypeFloat64:
+		if v, err := strconv.ParseFloat(raw, 64); err == nil {
+			return v
+		}
+	}
+	return raw
+}
+
+// BuildSelectColumns returns the explicit column list to query for table
+// given its negotiated StoreSchema, for use when the session variable
+// tidb_tiflash_systable_explicit_columns is enabled. Querying an explicit
+// list instead of `SELECT *` lets predicate pushdown avoid paying for
+// columns the caller never asked for, which matters on TiFlash's wider
+// system tables.
+func BuildSelectColumns(schema StoreSchema, wantColumns []string) []string {
+	cols := make([]string, 0, len(wantColumns))
+	for _, want := range wantColumns {
+		if schema.Has(want) {
+			cols = append(cols, want)
+		}
+	}
+	return cols
+}
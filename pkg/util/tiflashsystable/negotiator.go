@@ -0,0 +1,138 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tiflashsystable negotiates, per TiFlash store, which columns of
+// its `system.dt_segments`/`system.dt_tables` tables are actually available,
+// so the information_schema TIFLASH_SEGMENTS/TIFLASH_TABLES readers can stop
+// assuming the latest column set and filling gaps with unexplained <nil>s.
+package tiflashsystable
+
+import "sync"
+
+// ColumnMapping resolves one TiDB-facing column name to either the TiFlash
+// column that currently backs it, a legacy column name that means the same
+// thing on older TiFlash versions, or neither if the store genuinely
+// doesn't have an equivalent.
+type ColumnMapping struct {
+	// Current is the column name on current TiFlash versions.
+	Current string
+	// Legacy lists older column names that mean the same thing, newest
+	// first, so StoreSchema.Resolve can back-fill from them when Current isn't
+	// reported by an older store.
+	Legacy []string
+}
+
+// renameTable records known TiFlash column renames per system table, so a
+// future rename only needs a new entry here instead of a fresh <nil> fallout
+// in the reader. Keyed by system table name.
+var renameTable = map[string][]ColumnMapping{
+	"dt_segments": {
+		{Current: "delta_rate_rows", Legacy: []string{"delta_rate"}},
+		{Current: "delta_rate_segments", Legacy: []string{}},
+	},
+	"dt_tables": {
+		{Current: "total_rows", Legacy: []string{"rows"}},
+	},
+}
+
+// StoreSchema is the negotiated column availability for one TiFlash store's
+// system table, cached by Negotiator.
+type StoreSchema struct {
+	// TiFlashVersion is the version reported by the store at negotiation
+	// time, e.g. read from cluster_info.
+	TiFlashVersion string
+	// Columns is the set of columns the store reported for the table, as
+	// returned by `SELECT name FROM system.columns WHERE database='system'
+	// AND table=<table>`.
+	Columns map[string]struct{}
+}
+
+// Has reports whether the store reports column.
+func (s StoreSchema) Has(column string) bool {
+	_, ok := s.Columns[column]
+	return ok
+}
+
+// Resolve returns the column name to actually query for a TiDB-facing
+// column in table, given this store's negotiated schema: the current name
+// if the store has it, else the newest legacy name it has, else "" with
+// ok=false to mean the store has no equivalent at all.
+func (s StoreSchema) Resolve(table, tidbColumn string) (storeColumn string, ok bool) {
+	mappings := renameTable[table]
+	for _, m := range mappings {
+		if m.Current != tidbColumn {
+			continue
+		}
+		if s.Has(m.Current) {
+			return m.Current, true
+		}
+		for _, legacy := range m.Legacy {
+			if s.Has(legacy) {
+				return legacy, true
+			}
+		}
+		return "", false
+	}
+	// No known rename: the TiDB column name and the store's column name
+	// are assumed to be the same.
+	if s.Has(tidbColumn) {
+		return tidbColumn, true
+	}
+	return "", false
+}
+
+// storeKey identifies one negotiated StoreSchema.
+type storeKey struct {
+	storeAddr string
+	table     string
+}
+
+// Negotiator caches each store's negotiated StoreSchema by store address,
+// table, and TiFlash version, so a store's columns are only queried once per
+// version it's observed running.
+type Negotiator struct {
+	mu    sync.RWMutex
+	cache map[storeKey]StoreSchema
+}
+
+// NewNegotiator creates an empty Negotiator.
+func NewNegotiator() *Negotiator {
+	return &Negotiator{cache: make(map[storeKey]StoreSchema)}
+}
+
+// Get returns the cached StoreSchema for storeAddr/table if one was recorded
+// for the given tiflashVersion; a version bump invalidates the cache entry,
+// since the store may have gained or dropped columns across the upgrade.
+func (n *Negotiator) Get(storeAddr, table, tiflashVersion string) (StoreSchema, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	schema, ok := n.cache[storeKey{storeAddr, table}]
+	if !ok || schema.TiFlashVersion != tiflashVersion {
+		return StoreSchema{}, false
+	}
+	return schema, true
+}
+
+// Put records the columns a store reported for table at tiflashVersion,
+// e.g. from `SELECT name FROM system.columns WHERE database='system' AND
+// table=<table>`.
+func (n *Negotiator) Put(storeAddr, table, tiflashVersion string, columns []string) {
+	set := make(map[string]struct{}, len(columns))
+	for _, c := range columns {
+		set[c] = struct{}{}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cache[storeKey{storeAddr, table}] = StoreSchema{TiFlashVersion: tiflashVersion, Columns: set}
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiflashsystable
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Predicate is an equality or IN-list condition extracted from a query's
+// access conditions on one of TIDB_DATABASE/TIDB_TABLE/TIDB_TABLE_ID, to be
+// pushed down into the `WHERE` clause TiDB sends a TiFlash instance instead
+// of filtering every returned row in TiDB.
+type Predicate struct {
+	// Column is the TiFlash-side column name the predicate applies to:
+	// "tidb_database", "tidb_table", or "tidb_table_id".
+	Column string
+	// Values holds one value for an equality predicate, or several for an
+	// IN-list/OR-of-equalities predicate.
+	Values []string
+}
+
+// defaultLimit mirrors the unconditional `LIMIT 0, 1024` the segments/tables
+// readers issue today when no predicate narrows the row count.
+const defaultLimit = 1024
+
+// BuildQuery builds the `SELECT ... FROM system.<table> [WHERE ...] LIMIT
+// ...` statement to send a TiFlash instance for the given predicates,
+// translating equality/IN conditions on TIDB_DATABASE/TIDB_TABLE/
+// TIDB_TABLE_ID into a pushed-down WHERE clause instead of the unconditional
+// scan the readers issue today. columns is the explicit column list to
+// select; pass nil to fall back to `SELECT *`.
+func BuildQuery(systemTable string, columns []string, predicates []Predicate, limit uint64) string {
+	selectList := "*"
+	if len(columns) > 0 {
+		selectList = strings.Join(columns, ", ")
+	}
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT %s FROM system.%s", selectList, systemTable)
+	if where := buildWhereClause(predicates); where != "" {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(where)
+	}
+	fmt.Fprintf(&sb, " LIMIT 0, %d", limit)
+	return sb.String()
+}
+
+// buildWhereClause ANDs together one IN-list condition per predicate
+// column, so a single-value predicate becomes `col = 'v'`, a multi-value
+// predicate becomes `col IN ('v1', 'v2')`, and several predicate columns are
+// combined with AND, e.g. for a conjunction of TIDB_DATABASE and
+// TIDB_TABLE_ID predicates.
+func buildWhereClause(predicates []Predicate) string {
+	clauses := make([]string, 0, len(predicates))
+	for _, p := range predicates {
+		if len(p.Values) == 0 {
+			continue
+		}
+		if len(p.Values) == 1 {
+			clauses = append(clauses, fmt.Sprintf("%s = %s", p.Column, quote(p.Column, p.Values[0])))
+			continue
+		}
+		quoted := make([]string, len(p.Values))
+		for i, v := range p.Values {
+			quoted[i] = quote(p.Column, v)
+		}
+		clauses = append(clauses, fmt.Sprintf("%s IN (%s)", p.Column, strings.Join(quoted, ", ")))
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// quote renders a predicate value as a SQL literal. tidb_table_id is
+// numeric on the TiFlash side; every other supported column is a string.
+func quote(column, value string) string {
+	if column == "tidb_table_id" {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiflashsystable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildQuery(t *testing.T) {
+	cases := []struct {
+		name       string
+		predicates []Predicate
+		limit      uint64
+		expected   string
+	}{
+		{
+			name:       "no predicate",
+			predicates: nil,
+			expected:   "SELECT * FROM system.dt_segments LIMIT 0, 1024",
+		},
+		{
+			name:       "single disjunction",
+			predicates: []Predicate{{Column: "tidb_database", Values: []string{"test"}}},
+			expected:   "SELECT * FROM system.dt_segments WHERE tidb_database = 'test' LIMIT 0, 1024",
+		},
+		{
+			name: "multi conjunction",
+			predicates: []Predicate{
+				{Column: "tidb_database", Values: []string{"test"}},
+				{Column: "tidb_table", Values: []string{"t1"}},
+			},
+			expected: "SELECT * FROM system.dt_segments WHERE tidb_database = 'test' AND tidb_table = 't1' LIMIT 0, 1024",
+		},
+		{
+			name:       "in list",
+			predicates: []Predicate{{Column: "tidb_table_id", Values: []string{"1", "2", "3"}}},
+			limit:      100,
+			expected:   "SELECT * FROM system.dt_segments WHERE tidb_table_id IN (1, 2, 3) LIMIT 0, 100",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, BuildQuery("dt_segments", nil, c.predicates, c.limit))
+		})
+	}
+}
+
+func TestBuildQueryExplicitColumns(t *testing.T) {
+	got := BuildQuery("dt_tables", []string{"tidb_database", "tidb_table"}, nil, 0)
+	require.Equal(t, "SELECT tidb_database, tidb_table FROM system.dt_tables LIMIT 0, 1024", got)
+}